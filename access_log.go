@@ -0,0 +1,40 @@
+package log
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const accessLogMsg = "access"
+
+// AccessEntry is one request handled by a web service.
+type AccessEntry struct {
+	Method  string
+	Path    string
+	Status  int
+	Bytes   int64
+	Latency time.Duration
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (e AccessEntry) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("method", e.Method)
+	enc.AddString("path", e.Path)
+	enc.AddInt("status", e.Status)
+	enc.AddInt64("bytes", e.Bytes)
+	enc.AddDuration("latency", e.Latency)
+	return nil
+}
+
+// AccessLog - Write one access log line into access.log, the trace id is taken from ctx.
+func AccessLog(ctx context.Context, entry AccessEntry, fields ...zap.Field) {
+	getAccessLogger(ctx).Info(accessLogMsg, append([]zap.Field{zap.Inline(entry)}, fields...)...)
+}
+
+func getAccessLogger(ctx context.Context) *zap.Logger {
+	traceID := GetTraceIDFromCtx(ctx)
+	return GetAccessLogger().With(zap.String(TraceKey, traceID))
+}
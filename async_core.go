@@ -0,0 +1,144 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/caser789/logger/internal/writer"
+	"go.uber.org/zap/zapcore"
+)
+
+// asyncEntry is a log waiting in the queue of an asyncCore to be encoded and written by core.
+type asyncEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// asyncQueue is the queue shared by an asyncCore and the cores derived from it by With,
+// read by one goroutine encoding and writing the logs.
+type asyncQueue struct {
+	queue *writer.Queue[asyncEntry]
+	// flushes are the requests of Sync, answered once the logs queued before are written.
+	flushes chan chan error
+	root    zapcore.Core
+	// done stops the goroutine, which closes exited once the queued logs are written.
+	done      chan struct{}
+	exited    chan struct{}
+	closeOnce sync.Once
+}
+
+// asyncCore encodes and writes the logs in the background, so the caller only queues them, see Config.Async.
+// The logs are handled according to the QueueFullPolicy when the queue is full.
+// The logs above error level are written by the caller, after the queued logs, as they may end the process.
+type asyncCore struct {
+	zapcore.Core
+	q *asyncQueue
+}
+
+func newAsyncCore(core zapcore.Core, size int, policy writer.QueueFullPolicy) *asyncCore {
+	q := &asyncQueue{
+		queue:   writer.NewQueue[asyncEntry](size, policy),
+		flushes: make(chan chan error),
+		root:    core,
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	go q.run()
+	return &asyncCore{Core: core, q: q}
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.exited)
+	for {
+		select {
+		case e := <-q.queue.C():
+			e.write()
+		case done := <-q.flushes:
+			q.drain()
+			done <- q.root.Sync()
+		case <-q.done:
+			q.drain()
+			return
+		}
+	}
+}
+
+// drain writes the logs in the queue.
+func (q *asyncQueue) drain() {
+	for {
+		select {
+		case e := <-q.queue.C():
+			e.write()
+		default:
+			return
+		}
+	}
+}
+
+func (e asyncEntry) write() {
+	// nobody to return the error to, like a zap logger without error output
+	_ = e.core.Write(e.ent, e.fields)
+}
+
+// QueueStats - Return the counters of the queue, see AsyncQueueStats.
+func (c *asyncCore) QueueStats() writer.QueueStats {
+	return c.q.queue.Stats()
+}
+
+func (c *asyncCore) With(fields []zapcore.Field) zapcore.Core {
+	return &asyncCore{Core: c.Core.With(fields), q: c.q}
+}
+
+func (c *asyncCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write queues the log. The fields are encoded later, so the values they refer to must not be modified.
+func (c *asyncCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level > zapcore.ErrorLevel {
+		_ = c.Sync()
+		return c.Core.Write(ent, fields)
+	}
+	c.q.queue.Push(asyncEntry{
+		core:   c.Core,
+		ent:    ent,
+		fields: append([]zapcore.Field(nil), fields...),
+	})
+	return nil
+}
+
+// Sync writes the logs queued before the call, and syncs the wrapped core.
+func (c *asyncCore) Sync() error {
+	done := make(chan error)
+	select {
+	case c.q.flushes <- done:
+		return <-done
+	case <-c.q.exited:
+		return c.Core.Sync()
+	}
+}
+
+// Close writes the queued logs and stops the goroutine, the logs written later are dropped.
+// The wrapped core is closed with its own writer.
+func (c *asyncCore) Close() error {
+	c.q.closeOnce.Do(func() {
+		c.q.queue.Close()
+		close(c.q.done)
+	})
+	<-c.q.exited
+	return nil
+}
+
+// withAsync - Return core writing its logs in the background if opt is async, registered in AsyncQueueStats
+// and closed with its logger.
+func withAsync(core zapcore.Core, opt option) zapcore.Core {
+	if !opt.Async {
+		return core
+	}
+	c := newAsyncCore(core, opt.AsyncSize, queueFullPolicy(opt.QueuePolicy))
+	registerSink(sinkName(opt), c)
+	return c
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caser789/logger/internal/writer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// blockingCore writes into core once released.
+type blockingCore struct {
+	zapcore.Core
+	release chan struct{}
+}
+
+func (c *blockingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	<-c.release
+	return c.Core.Write(ent, fields)
+}
+
+func TestAsyncCoreDrops(t *testing.T) {
+	inner, logs := observer.New(DebugLvl)
+	release := make(chan struct{})
+	c := newAsyncCore(&blockingCore{Core: inner, release: release}, 2, writer.QueueDrop)
+	l := zap.New(c)
+
+	// the first log blocks the writing goroutine, 2 are queued, the others are dropped
+	for i := 0; i < 10; i++ {
+		l.Info("queued", zap.Int("i", i))
+	}
+	stats := c.QueueStats()
+	assert.GreaterOrEqual(t, stats.Dropped, uint64(7))
+	assert.Equal(t, uint64(10), stats.Enqueued+stats.Dropped)
+
+	close(release)
+	assert.Nil(t, l.Sync())
+	assert.Equal(t, int(stats.Enqueued), logs.Len())
+	for i, e := range logs.All() {
+		assert.Equal(t, int64(i), e.ContextMap()["i"])
+	}
+}
+
+func TestAsyncCore(t *testing.T) {
+	config := &Config{Path: t.TempDir(), Async: true, AsyncQueueSize: 100}
+	opt := getOption(config, "async", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})
+	l := newLogger(opt).With(zap.String("k", "v"))
+
+	for i := 0; i < 50; i++ {
+		l.Info("in the background")
+	}
+	l.Debug("dropped by level")
+	assert.Nil(t, l.Sync())
+
+	data, err := os.ReadFile(filepath.Join(config.Path, "async.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, 50, strings.Count(string(data), "in the background"))
+	assert.Equal(t, 50, strings.Count(string(data), `"k":"v"`))
+	assert.NotContains(t, string(data), "dropped by level")
+
+	stats := AsyncQueueStats()[opt.Filename]
+	assert.Equal(t, uint64(50), stats.Enqueued)
+	assert.Zero(t, stats.Dropped)
+}
+
+func TestAsyncCoreErrorLevelSync(t *testing.T) {
+	inner, logs := observer.New(DebugLvl)
+	l := zap.New(newAsyncCore(inner, 10, writer.QueueDrop))
+
+	l.Info("queued")
+	l.DPanic("written by the caller")
+	// the queued log is written first
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, "queued", logs.All()[0].Message)
+}
+
+func TestAsyncCoreClosed(t *testing.T) {
+	inner, logs := observer.New(DebugLvl)
+	c := newAsyncCore(inner, 10, writer.QueueDrop)
+	l := zap.New(c)
+
+	l.Info("queued")
+	assert.Nil(t, c.Close())
+	// the goroutine exited once the queued log was written
+	<-c.q.exited
+	assert.Equal(t, 1, logs.Len())
+
+	l.Info("dropped")
+	assert.Nil(t, l.Sync())
+	assert.Nil(t, c.Close())
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, uint64(1), c.QueueStats().Dropped)
+}
+
+func TestAsyncStdoutCore(t *testing.T) {
+	opt := getStdoutOption(&Config{}, func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})
+	opt.Async = true
+	newLogger(opt)
+
+	_, ok := AsyncQueueStats()[stdoutSinkName]
+	assert.True(t, ok)
+	sinks := sinksOf(stdoutSinkName)
+	assert.Len(t, sinks, 1)
+
+	assert.Nil(t, closeSinks(sinks))
+	_, ok = AsyncQueueStats()[stdoutSinkName]
+	assert.False(t, ok)
+	<-sinks[0].(*asyncCore).q.exited
+}
+
+func benchmarkAsync(b *testing.B, async bool) {
+	config := &Config{Path: b.TempDir(), Async: async, AsyncQueueSize: 100000}
+	l := newLogger(getOption(config, "bench", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	}))
+	b.Cleanup(func() {
+		_ = l.Sync()
+	})
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Info("message", zap.String("user_id", "u1"), zap.Int("count", 3))
+		}
+	})
+}
+
+func BenchmarkSyncCore(b *testing.B) {
+	benchmarkAsync(b, false)
+}
+
+func BenchmarkAsyncCore(b *testing.B) {
+	benchmarkAsync(b, true)
+}
@@ -0,0 +1,26 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// auditMutex keeps the audit logs in the order of their sequence numbers.
+	auditMutex sync.Mutex
+	auditSeq   uint64
+)
+
+// Audit - Write one audit log line, with action as message, into audit.log, e.g. for the security reviews.
+// The line is written into the file before Audit returns, never sampled, and carries the trace id of ctx
+// and a sequence number under "seq", increasing by one with each audit log of the process.
+func Audit(ctx context.Context, action string, fields ...zap.Field) {
+	l := GetAuditLogger().WithOptions(helperSkip).With(zap.String(TraceKey, GetTraceIDFromCtx(ctx)))
+
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	auditSeq++
+	l.Info(action, append([]zap.Field{zap.Uint64(SeqKey, auditSeq)}, fields...)...)
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAudit(t *testing.T) {
+	config := &Config{Path: t.TempDir(), FlushInterval: time.Hour}
+	oldAuditLogger := GetAuditLogger()
+	initAuditLogger(config)
+	defer func() {
+		_ = CloseAll()
+		auditLogger.Store(oldAuditLogger)
+	}()
+
+	ctx, _ := WithNewTraceLog("audit", context.Background())
+	auditMutex.Lock()
+	seq := auditSeq
+	auditMutex.Unlock()
+	Audit(ctx, "user.delete", zap.String("user", "alice"))
+	Audit(context.Background(), "user.create")
+
+	// written without Sync, despite the flush interval
+	data, err := os.ReadFile(filepath.Join(config.Path, AuditLogFileName+".log"))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "/audit_log_test.go:")
+	assert.Contains(t, lines[0], "|"+GetTraceIDFromCtx(ctx)+"|")
+	assert.True(t, strings.HasSuffix(lines[0], fmt.Sprintf(`|user.delete|{"seq":%d,"user":"alice"}`, seq+1)), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], fmt.Sprintf(`|user.create|{"seq":%d}`, seq+2)), lines[1])
+
+	assert.False(t, checkLogFileNameValid(DebugLvl, AuditLogFileName))
+	assert.Contains(t, ResolveLogPaths(config), filepath.Join(config.Path, AuditLogFileName+".log"))
+}
@@ -1,6 +1,17 @@
 package log
 
-import "github.com/caser789/logger/internal/utils/env"
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/caser789/logger/internal/utils/env"
+	"github.com/hashicorp/go-multierror"
+	"gopkg.in/yaml.v3"
+)
 
 var (
 	defaultConfig = &Config{
@@ -21,3 +32,159 @@ func getDefaultConfig() *Config {
 	}
 	return defaultConfig
 }
+
+// LoadConfig - Read a Config from YAML, or JSON which is a subset of YAML, e.g.
+//
+//	level: info
+//	splitLevel: warn
+//	printToStd: userlog,syslog
+//	flushInterval: 100ms
+//
+// The keys are the field names starting in lowercase. Level and SplitLevel are read from their names
+// (debug, info, warn, error...), an unknown name is an error.
+func LoadConfig(r io.Reader) (*Config, error) {
+	config := &Config{}
+	if err := yaml.NewDecoder(r).Decode(config); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("log: invalid config: %w", err)
+	}
+	if config.SplitLevel != "" && config.SplitLevel != SplitNone {
+		if _, ok := checkLevel(config.SplitLevel); !ok {
+			return nil, fmt.Errorf("log: invalid config: unknown split level %q, expecting one of debug, info, warn, error or none", config.SplitLevel)
+		}
+	}
+	return config, nil
+}
+
+// validateConfig - Return the errors of config which InitLogger would otherwise silently fix or ignore:
+// the file names reserved by the other logs, a RouteFunc writing no level, an unknown split level or rotation mode,
+// negative intervals and sizes, a PrintToStd contradicting PrintToStdout and journald on a platform without it.
+func validateConfig(config *Config) error {
+	var res *multierror.Error
+	invalid := func(format string, args ...interface{}) {
+		res = multierror.Append(res, fmt.Errorf("log: invalid config: "+format, args...))
+	}
+
+	reserved := map[string]bool{SysLogFileName: true, SysErrorLogFileName: true, AccessLogFileName: true,
+		AuditLogFileName: true}
+	for _, name := range nameMap {
+		reserved[name] = true
+	}
+	if reserved[config.LogFileName] {
+		invalid("log file name %q is reserved", config.LogFileName)
+	}
+	if reserved[config.TracingLogFileName] {
+		invalid("tracing log file name %q is reserved", config.TracingLogFileName)
+	}
+	logFileName, tracingLogFileName := config.LogFileName, config.TracingLogFileName
+	if logFileName == "" {
+		logFileName = DefaultLogFileName
+	}
+	if tracingLogFileName == "" {
+		tracingLogFileName = DefaultTracingFileName
+	}
+	if logFileName == tracingLogFileName {
+		invalid("log file name and tracing log file name are both %q", logFileName)
+	}
+
+	if config.RouteFunc != nil {
+		written := false
+		for lvl := DebugLvl; lvl <= FatalLvl; lvl++ {
+			name, write := config.RouteFunc(lvl)
+			written = written || write
+			if write && routeNameReserved(config, name) {
+				invalid("route file name %q of level %s is reserved", name, lvl)
+			}
+		}
+		if !written {
+			invalid("route func drops the logs of every level")
+		}
+	}
+
+	if config.SplitLevel != "" && config.SplitLevel != SplitNone {
+		if _, ok := checkLevel(config.SplitLevel); !ok {
+			invalid("unknown split level %q, expecting one of debug, info, warn, error or none", config.SplitLevel)
+		}
+	}
+	if config.RotationMode != "" && config.RotationMode != RotationRename && config.RotationMode != RotationCopyTruncate {
+		invalid("unknown rotation mode %q, expecting %s or %s", config.RotationMode, RotationRename, RotationCopyTruncate)
+	}
+	if config.RotateInterval < 0 {
+		invalid("negative rotate interval %v", config.RotateInterval)
+	}
+	if config.FlushInterval < 0 {
+		invalid("negative flush interval %v", config.FlushInterval)
+	}
+	if config.AsyncQueueSize < 0 {
+		invalid("negative async queue size %d", config.AsyncQueueSize)
+	}
+	if config.CompressionLevel < gzip.HuffmanOnly || config.CompressionLevel > gzip.BestCompression {
+		invalid("unknown compression level %d, expecting %d to %d", config.CompressionLevel,
+			gzip.HuffmanOnly, gzip.BestCompression)
+	}
+
+	if config.UseJournald {
+		if err := journaldSupported(); err != nil {
+			invalid("use journald: %v", err)
+		}
+	}
+
+	if config.PrintToStd&^PrintToStd_ALL != 0 {
+		invalid("unknown print to std %d", config.PrintToStd)
+	} else if config.PrintToStdout && config.PrintToStd != PrintToStd_NONE && config.PrintToStd != PrintToStd_ALL {
+		invalid("print to stdout prints all the logs, but print to std is %d", config.PrintToStd)
+	}
+	return res.ErrorOrNil()
+}
+
+// LoadConfigFile - Read a Config from the YAML or JSON file at path, see LoadConfig.
+func LoadConfigFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadConfig(f)
+}
+
+var printToStdNames = map[string]PrintToStd{
+	"none":    PrintToStd_NONE,
+	"userlog": PrintToStd_USERLOG,
+	"syslog":  PrintToStd_SYSLOG,
+	"tracing": PrintToStd_TRACING,
+	"all":     PrintToStd_ALL,
+}
+
+// ParsePrintToStd - Parse a comma separated list of the kinds of logs printed into stdout,
+// among none, userlog, syslog, tracing and all, e.g. "userlog,syslog", into the PrintToStd combining them.
+// The numeric value of the PrintToStd (e.g. "3") is also accepted.
+func ParsePrintToStd(s string) (PrintToStd, error) {
+	if n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 8); err == nil {
+		if PrintToStd(n)&^PrintToStd_ALL != 0 {
+			return PrintToStd_NONE, fmt.Errorf("log: invalid print to std %d", n)
+		}
+		return PrintToStd(n), nil
+	}
+	var p PrintToStd
+	for _, token := range strings.Split(s, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token == "" {
+			continue
+		}
+		v, ok := printToStdNames[token]
+		if !ok {
+			return PrintToStd_NONE, fmt.Errorf("log: invalid print to std %q, expecting none, userlog, syslog, tracing or all", token)
+		}
+		p |= v
+	}
+	return p, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParsePrintToStd.
+func (p *PrintToStd) UnmarshalText(text []byte) error {
+	v, err := ParsePrintToStd(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	config, err := LoadConfigFile("testdata/config.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, &Config{
+		Level:         WarnLvl,
+		PrintToStd:    PrintToStd_ALL,
+		Compress:      true,
+		Path:          "/var/log/app",
+		LogFileName:   "app",
+		SplitLevel:    SplitError,
+		FlushInterval: 100 * time.Millisecond,
+		RotationMode:  RotationCopyTruncate,
+		KafkaBrokers:  []string{"10.0.0.1:9092", "10.0.0.2:9092"},
+		WithRegion:    true,
+	}, config)
+
+	_, err = LoadConfigFile("testdata/missing.yaml")
+	assert.NotNil(t, err)
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig(strings.NewReader(`{"level": "debug", "splitLevel": "none", "printToStd": 1}`))
+	assert.Nil(t, err)
+	assert.Equal(t, DebugLvl, config.Level)
+	assert.Equal(t, SplitNone, config.SplitLevel)
+	assert.Equal(t, PrintToStd_USERLOG, config.PrintToStd)
+
+	config, err = LoadConfig(strings.NewReader(""))
+	assert.Nil(t, err)
+	assert.Equal(t, &Config{}, config)
+
+	_, err = LoadConfig(strings.NewReader("level: verbose"))
+	assert.ErrorContains(t, err, `unrecognized level: "verbose"`)
+
+	_, err = LoadConfig(strings.NewReader("splitLevel: verbose"))
+	assert.ErrorContains(t, err, `unknown split level "verbose"`)
+}
+
+func TestParsePrintToStd(t *testing.T) {
+	tests := []struct {
+		in   string
+		want PrintToStd
+	}{
+		{"", PrintToStd_NONE},
+		{"none", PrintToStd_NONE},
+		{"userlog", PrintToStd_USERLOG},
+		{"userlog,syslog", PrintToStd_USERLOG | PrintToStd_SYSLOG},
+		{" syslog , Tracing ", PrintToStd_SYSLOG | PrintToStd_TRACING},
+		{"none,tracing", PrintToStd_TRACING},
+		{"userlog,all", PrintToStd_ALL},
+		{"5", PrintToStd_USERLOG | PrintToStd_TRACING},
+	}
+	for _, tt := range tests {
+		got, err := ParsePrintToStd(tt.in)
+		assert.Nil(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	_, err := ParsePrintToStd("userlog,stdout")
+	assert.ErrorContains(t, err, `invalid print to std "stdout"`)
+	_, err = ParsePrintToStd("8")
+	assert.NotNil(t, err)
+
+	config, err := LoadConfig(strings.NewReader(`{"printToStd": "syslog,tracing"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, PrintToStd_SYSLOG|PrintToStd_TRACING, config.PrintToStd)
+	_, err = LoadConfig(strings.NewReader("printToStd: everything"))
+	assert.NotNil(t, err)
+}
+
+func TestPrintToStdCombination(t *testing.T) {
+	config := &Config{Path: t.TempDir(), PrintToStd: PrintToStd_SYSLOG | PrintToStd_TRACING}
+	tracingLoggerInitOnce.Do(func() {})
+	initTracingLogger(config)
+	tracingLogger.Load().Info("to stdout")
+	_ = tracingLogger.Load().Sync()
+
+	_, err := os.Stat(filepath.Join(config.Path, DefaultTracingFileName+".log"))
+	assert.True(t, os.IsNotExist(err), err)
+}
+
+func TestInitLoggerE(t *testing.T) {
+	tests := []struct {
+		config *Config
+		err    string
+		errs   int
+	}{
+		{&Config{LogFileName: "warn"}, `log file name "warn" is reserved`, 1},
+		{&Config{LogFileName: SysLogFileName}, `log file name "sys" is reserved`, 1},
+		{&Config{TracingLogFileName: AccessLogFileName}, `tracing log file name "access" is reserved`, 1},
+		{&Config{TracingLogFileName: DefaultLogFileName}, `log file name and tracing log file name are both "server"`, 1},
+		{&Config{SplitLevel: "fatal"}, `unknown split level "fatal"`, 1},
+		{&Config{RotationMode: "move"}, `unknown rotation mode "move"`, 1},
+		{&Config{RotateInterval: -time.Hour}, "negative rotate interval -1h0m0s", 1},
+		{&Config{FlushInterval: -time.Millisecond}, "negative flush interval -1ms", 1},
+		{&Config{AsyncQueueSize: -1}, "negative async queue size -1", 1},
+		{&Config{CompressionLevel: 10}, "unknown compression level 10, expecting -2 to 9", 1},
+		{&Config{CompressionLevel: -3}, "unknown compression level -3", 1},
+		{&Config{PrintToStd: 8}, "unknown print to std 8", 1},
+		{&Config{PrintToStdout: true, PrintToStd: PrintToStd_USERLOG}, "print to stdout prints all the logs", 1},
+		// one error by level, from debug to fatal
+		{&Config{RouteFunc: func(LogLevel) (string, bool) { return AuditLogFileName, true }},
+			`route file name "audit" of level debug is reserved`, 7},
+		{&Config{TracingLogFileName: "trace", RouteFunc: func(LogLevel) (string, bool) { return "trace", true }},
+			`route file name "trace" of level debug is reserved`, 7},
+		{&Config{RouteFunc: func(LogLevel) (string, bool) { return "", true }}, `route file name "" of level debug is reserved`, 7},
+		{&Config{RouteFunc: func(LogLevel) (string, bool) { return "app", false }}, "route func drops the logs of every level", 1},
+	}
+	for _, tt := range tests {
+		err := InitLoggerE(tt.config)
+		assert.ErrorContains(t, err, tt.err)
+		var merr *multierror.Error
+		if assert.ErrorAs(t, err, &merr) {
+			assert.Len(t, merr.Errors, tt.errs, tt.err)
+		}
+	}
+
+	// all the errors are returned
+	err := validateConfig(&Config{LogFileName: "info", SplitLevel: "all", RotateInterval: -1})
+	assert.ErrorContains(t, err, "3 errors occurred")
+
+	for _, config := range []*Config{
+		{},
+		{LogFileName: "app", TracingLogFileName: "app_tracing", SplitLevel: SplitWarn, RotationMode: RotationCopyTruncate},
+		{PrintToStdout: true, PrintToStd: PrintToStd_ALL},
+		{PrintToStd: PrintToStd_USERLOG | PrintToStd_SYSLOG},
+		{Compress: true, CompressionLevel: gzip.BestSpeed},
+		{Compress: true, CompressionLevel: gzip.HuffmanOnly},
+		{RouteFunc: func(lvl LogLevel) (string, bool) { return DefaultLogFileName, lvl >= InfoLvl }},
+	} {
+		assert.Nil(t, validateConfig(config), "%+v", config)
+	}
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"github.com/caser789/logger/internal/lumberjack"
 	"github.com/caser789/logger/internal/utils/env"
 	"go.uber.org/zap"
 )
@@ -25,13 +26,42 @@ var (
 type CustomizeOption func(*option)
 
 // NewLogger will return a customized logger by CustomizeConfig.If config.LogFileName is empty,will write into customize.log.
+// Its log file is never closed, not even by CloseAll, nor counted by WriterStats, so it isn't kept by the package
+// once the logger is dropped. Use NewClosableLogger for the loggers whose file must be released.
 func NewLogger(opts ...CustomizeOption) *zap.Logger {
-	optCopy := defaultOptions
+	optCopy := *defaultOptions
 	for _, o := range opts {
-		o(optCopy)
+		o(&optCopy)
 	}
+	optCopy.Untracked = true
 
-	return newLogger(*optCopy)
+	return newLogger(optCopy)
+}
+
+// ClosableLogger - A customized logger whose log file can be closed, see NewClosableLogger.
+type ClosableLogger struct {
+	*zap.Logger
+	writer *lumberjack.Logger
+}
+
+// NewClosableLogger - Like NewLogger, but the log file of the returned logger can be closed with Close,
+// for the services creating many customized loggers.
+func NewClosableLogger(opts ...CustomizeOption) *ClosableLogger {
+	optCopy := *defaultOptions
+	for _, o := range opts {
+		o(&optCopy)
+	}
+
+	l := newLogger(optCopy)
+	return &ClosableLogger{Logger: l, writer: fileWriterOf(optCopy)}
+}
+
+// Close - Flush the logs and close the log file. Logging with the logger afterwards reopens the file.
+func (l *ClosableLogger) Close() error {
+	if l.writer == nil {
+		return l.Sync()
+	}
+	return closeFileWriters([]*lumberjack.Logger{l.writer})
 }
 
 func WithLogFileName(logPath, fileName string) CustomizeOption {
@@ -54,3 +84,17 @@ func WithPrintToStdout(printToStdout bool) CustomizeOption {
 		o.Stdout = printToStdout
 	}
 }
+
+// WithConsoleSeparator - Separate the columns of the logs with separator, see Config.ConsoleSeparator.
+func WithConsoleSeparator(separator string) CustomizeOption {
+	return func(o *option) {
+		o.Separator = separator
+	}
+}
+
+// WithFieldPrefix - Prepend prefix to the keys of the fields, see Config.FieldPrefix.
+func WithFieldPrefix(prefix string) CustomizeOption {
+	return func(o *option) {
+		o.FieldPrefix = prefix
+	}
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// openFiles returns the number of file descriptors of the process opened on name,
+// or -1 if they can't be listed.
+func openFiles(t *testing.T, name string) int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	name, err = filepath.Abs(name)
+	assert.Nil(t, err)
+	n := 0
+	for _, fd := range fds {
+		if target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); err == nil && target == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestClosableLogger(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "closable.log")
+	l := NewClosableLogger(WithLogFileName(dir, "closable"))
+	l.Info("before close")
+	assert.NotEqual(t, 0, openFiles(t, name))
+
+	assert.Nil(t, l.Close())
+	data, err := os.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "before close")
+	assert.Contains(t, []int{-1, 0}, openFiles(t, name))
+	assert.NotContains(t, WriterStats(), name)
+	assert.Nil(t, os.Remove(name))
+}
+
+func TestNewLoggerUntracked(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "untracked.log")
+	l := NewLogger(WithLogFileName(dir, "untracked"))
+	l.Info("untracked")
+	assert.Nil(t, l.Sync())
+
+	data, err := os.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "untracked")
+	assert.NotContains(t, WriterStats(), name)
+	fileWritersMutex.Lock()
+	for w := range openFileWriters {
+		assert.NotEqual(t, name, w.Filename)
+	}
+	fileWritersMutex.Unlock()
+}
+
+func TestCloseAll(t *testing.T) {
+	config := &Config{}
+	l, _ := newTestLogger(t, config)
+	name := filepath.Join(config.Path, "test.log")
+	l.Info("before close all")
+	assert.NotEqual(t, 0, openFiles(t, name))
+
+	assert.Nil(t, CloseAll())
+	data, err := os.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "before close all")
+	assert.Contains(t, []int{-1, 0}, openFiles(t, name))
+	assert.Nil(t, os.Remove(name))
+}
+
+func TestWithFieldPrefix(t *testing.T) {
+	dir := t.TempDir()
+	l := NewClosableLogger(WithLogFileName(dir, "prefixed"), WithFieldPrefix("payments."))
+	l.Info("prefixed", zap.Int("id", 1))
+	assert.Nil(t, l.Close())
+	data, err := os.ReadFile(filepath.Join(dir, "prefixed.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `{"payments.id":1}`)
+
+	// the option doesn't leak into the next customized loggers
+	l = NewClosableLogger(WithLogFileName(dir, "plain"))
+	l.Info("plain", zap.Int("id", 1))
+	assert.Nil(t, l.Close())
+	data, err = os.ReadFile(filepath.Join(dir, "plain.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `{"id":1}`)
+}
@@ -0,0 +1,220 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+
+	"github.com/caser789/logger/internal/extension"
+	"github.com/caser789/logger/internal/trace"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// LinksKey is the key of the field logged by LinksField.
+	LinksKey = "links"
+	// SpanDepthKey is the key of the field logged by SpanDepthField.
+	SpanDepthKey = "span_depth"
+	// StackDepthKey is the key of the field logged by StackDepthField.
+	StackDepthKey = "stack_depth"
+)
+
+// stacktraceDisabled is set by Config.DisableStacktrace.
+var stacktraceDisabled atomic.Bool
+
+// ErrorField - Return a field logging err under the "error" key. For wrapped errors,
+// each message of the chain is logged under "errorVerbose", and for errors carrying
+// a stack trace (e.g. created by github.com/pkg/errors), the deepest stack is logged under "errorStack",
+// unless Config.DisableStacktrace is set. A nil err is skipped.
+func ErrorField(err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Inline(errorMarshaler{err: err})
+}
+
+type errorMarshaler struct {
+	err error
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (e errorMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("error", e.err.Error())
+
+	var chain []string
+	var stack string
+	for err := e.err; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, err.Error())
+		if stacktraceDisabled.Load() {
+			continue
+		}
+		if s := stackTrace(err); s != "" {
+			stack = s
+		}
+	}
+	if len(chain) > 1 {
+		if err := enc.AddArray("errorVerbose", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			for _, msg := range chain {
+				arr.AppendString(msg)
+			}
+			return nil
+		})); err != nil {
+			return err
+		}
+	}
+	if stack != "" {
+		enc.AddString("errorStack", stack)
+	}
+	return nil
+}
+
+// stackTrace returns the formatted frames of err if it has a `StackTrace()` method,
+// which is how github.com/pkg/errors exposes them.
+func stackTrace(err error) string {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+	return fmt.Sprintf("%+v", method.Call(nil)[0].Interface())
+}
+
+// SortedMapField - Return a field logging m as a nested object under key, with the keys sorted,
+// so the output is deterministic unlike zap.Any. A nil or empty m is logged as an empty object.
+func SortedMapField(key string, m map[string]string) zap.Field {
+	return zap.Object(key, sortedMap(m))
+}
+
+type sortedMap map[string]string
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (m sortedMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		enc.AddString(k, m[k])
+	}
+	return nil
+}
+
+// SliceField - Return a field logging at most maxElems elements of the slice or array s under key,
+// followed by "...(+N more)" if some are left out, to bound the size of the logs of big collections.
+// All the elements are logged if maxElems is not positive. Other values of s are logged like zap.Any.
+func SliceField(key string, s interface{}, maxElems int) zap.Field {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return zap.Any(key, s)
+	}
+	return zap.Array(key, cappedSlice{v: v, max: maxElems})
+}
+
+type cappedSlice struct {
+	v   reflect.Value
+	max int
+}
+
+// MarshalLogArray implements zapcore.ArrayMarshaler.
+func (s cappedSlice) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	n := s.v.Len()
+	if s.max > 0 && n > s.max {
+		n = s.max
+	}
+	for i := 0; i < n; i++ {
+		if err := enc.AppendReflected(s.v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	if more := s.v.Len() - n; more > 0 {
+		enc.AppendString(fmt.Sprintf("...(+%d more)", more))
+	}
+	return nil
+}
+
+// ByteSize - Return a field logging a number of bytes under key in a human readable form,
+// with one decimal and binary units from KiB, e.g. 512B or 1.2MiB. EncodingJSON logs the number.
+func ByteSize(key string, bytes int64) zap.Field {
+	return zap.Inline(byteSize{key: key, bytes: bytes})
+}
+
+type byteSize struct {
+	key   string
+	bytes int64
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (s byteSize) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if extension.IsJSONEncoder(enc) {
+		enc.AddInt64(s.key, s.bytes)
+		return nil
+	}
+	enc.AddString(s.key, formatByteSize(s.bytes))
+	return nil
+}
+
+func formatByteSize(bytes int64) string {
+	const unit = 1024
+	abs := bytes
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := abs / unit; n >= unit && exp < 5; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// LinksField - Return a field logging the trace ids of primary and others under the "links" key,
+// to record the requests a job follows from, e.g. when a batch combines several upstream requests.
+// Nil span contexts are skipped.
+func LinksField(primary trace.SpanContext, others ...trace.SpanContext) zap.Field {
+	return zap.Strings(LinksKey, trace.Links(primary, others...))
+}
+
+// SpanDepthField - Return a field logging the nesting depth of the span in ctx under the "span_depth" key,
+// 0 for a root span. It is skipped if ctx has no span context or one of the old format, see trace.SpanDepth.
+func SpanDepthField(ctx context.Context) zap.Field {
+	depth := trace.SpanDepth(GetSpanContext(ctx))
+	if depth < 0 {
+		return zap.Skip()
+	}
+	return zap.Int(SpanDepthKey, depth)
+}
+
+// StackDepthField - Return a field logging the number of frames on the stack of the calling goroutine
+// under the "stack_depth" key, to see how deep a recursion goes.
+// It walks the whole stack on each call, so its cost grows with the depth: use it for debugging only.
+func StackDepthField() zap.Field {
+	pcs := make([]uintptr, 64)
+	for {
+		// skip runtime.Callers and StackDepthField
+		n := runtime.Callers(2, pcs)
+		if n < len(pcs) {
+			return zap.Int(StackDepthKey, n)
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+}
+
+// IndexedField - Mark f as searchable for the log backend. With Config.FieldHints, it is written
+// in the "indexed" object of the log, otherwise like f.
+func IndexedField(f zap.Field) zap.Field {
+	return zap.Inline(extension.HintedField{Hint: extension.HintIndexed, Field: f})
+}
+
+// StoredField - Mark f as only retrievable with its log, not searchable. With Config.FieldHints,
+// it is written in the "stored" object of the log, otherwise like f.
+func StoredField(f zap.Field) zap.Field {
+	return zap.Inline(extension.HintedField{Hint: extension.HintStored, Field: f})
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/caser789/logger/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type testStack []string
+
+func (s testStack) Format(f fmt.State, verb rune) {
+	for _, frame := range s {
+		fmt.Fprintf(f, "\n%s", frame)
+	}
+}
+
+type stackError struct {
+	msg   string
+	stack testStack
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) StackTrace() testStack { return e.stack }
+
+func TestErrorField(t *testing.T) {
+	root := &stackError{msg: "connection refused", stack: testStack{"main.dial", "main.main"}}
+	err := fmt.Errorf("query user: %w", fmt.Errorf("get conn: %w", root))
+
+	l, read := newTestLogger(t, &Config{})
+	l.Error("failed", ErrorField(err))
+	line := read()
+	assert.Contains(t, line, `"error":"query user: get conn: connection refused"`)
+	assert.Contains(t, line, `"errorVerbose":["query user: get conn: connection refused","get conn: connection refused","connection refused"]`)
+	assert.Contains(t, line, `"errorStack":"\nmain.dial\nmain.main"`)
+}
+
+func TestErrorFieldPlainAndNil(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	l.Error("plain", ErrorField(errors.New("plain error")))
+	l.Error("nil", ErrorField(nil))
+	line := read()
+	assert.Contains(t, line, `"error":"plain error"`)
+	assert.NotContains(t, line, "errorVerbose")
+	assert.NotContains(t, line, "errorStack")
+	assert.Contains(t, line, "|nil\n")
+}
+
+func TestSortedMapField(t *testing.T) {
+	m := map[string]string{}
+	for _, k := range []string{"k", "b", "z", "a", "m", "c", "y", "d"} {
+		m[k] = "v" + k
+	}
+
+	l, read := newTestLogger(t, &Config{})
+	for i := 0; i < 10; i++ {
+		l.Info("map", SortedMapField("m", m))
+	}
+	l.Info("empty", SortedMapField("m", nil))
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Equal(t, 11, len(lines))
+	for _, line := range lines[:10] {
+		assert.True(t, strings.HasSuffix(line,
+			`{"m":{"a":"va","b":"vb","c":"vc","d":"vd","k":"vk","m":"vm","y":"vy","z":"vz"}}`), line)
+	}
+	assert.True(t, strings.HasSuffix(lines[10], `{"m":{}}`), lines[10])
+}
+
+func TestSliceField(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	l.Info("capped", SliceField("ids", []int{1, 2, 3, 4, 5}, 3))
+	l.Info("under", SliceField("names", [2]string{"a", "b"}, 3))
+	l.Info("uncapped", SliceField("ids", []int{1, 2}, 0))
+	l.Info("not a slice", SliceField("n", 7, 3))
+	l.Info("nil", SliceField("s", nil, 3))
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.True(t, strings.HasSuffix(lines[0], `{"ids":[1,2,3,"...(+2 more)"]}`), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], `{"names":["a","b"]}`), lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], `{"ids":[1,2]}`), lines[2])
+	assert.True(t, strings.HasSuffix(lines[3], `{"n":7}`), lines[3])
+	assert.True(t, strings.HasSuffix(lines[4], `{"s":null}`), lines[4])
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1023, "1023B"},
+		{1024, "1.0KiB"},
+		{1536, "1.5KiB"},
+		{1258291, "1.2MiB"},
+		{5 << 30, "5.0GiB"},
+		{3 << 40, "3.0TiB"},
+		{1 << 62, "4.0EiB"},
+		{-2048, "-2.0KiB"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, formatByteSize(tt.bytes), tt.bytes)
+	}
+
+	l, read := newTestLogger(t, &Config{})
+	l.Info("size", ByteSize("size", 1258291))
+	assert.Contains(t, read(), `{"size":"1.2MiB"}`)
+
+	// the raw number for the machines
+	l, read = newTestLogger(t, &Config{Encoding: EncodingJSON})
+	l.With(ByteSize("limit", 1<<20)).Info("size", ByteSize("size", 1258291))
+	assert.Contains(t, read(), `"msg":"size","limit":1048576,"size":1258291}`)
+}
+
+func TestLinksField(t *testing.T) {
+	scg := trace.NewSpanContextGenerator("")
+	primary, other := scg.NewSpanContext(), scg.NewSpanContext()
+
+	l, read := newTestLogger(t, &Config{})
+	l.Info("batch", LinksField(primary, nil, other))
+	assert.Contains(t, read(), `"links":["`+primary.String()+`","`+other.String()+`"]`)
+}
+
+func TestSpanDepthField(t *testing.T) {
+	root := trace.NewSpanContextGenerator("").NewSpanContext(trace.IsFromStressTest(true))
+	child := root.NewChildSpanContext()
+	old := trace.NewSpanContextGenerator("").NewSpanContext()
+
+	l, read := newTestLogger(t, &Config{})
+	l.Info("root", SpanDepthField(WithSpanContext(context.Background(), root)))
+	l.Info("child", SpanDepthField(WithSpanContext(context.Background(), child)))
+	l.Info("none", SpanDepthField(context.Background()))
+	l.Info("old", SpanDepthField(WithSpanContext(context.Background(), old)))
+	out := read()
+	assert.Contains(t, out, `|root|{"span_depth":0}`)
+	assert.Contains(t, out, `|child|{"span_depth":1}`)
+	assert.Contains(t, out, "|none\n")
+	assert.Contains(t, out, "|old\n")
+}
+
+func stackDepthAt(depth int) zap.Field {
+	if depth == 0 {
+		return StackDepthField()
+	}
+	return stackDepthAt(depth - 1)
+}
+
+func TestStackDepthField(t *testing.T) {
+	shallow, deep := stackDepthAt(0), stackDepthAt(200)
+	assert.Equal(t, StackDepthKey, shallow.Key)
+	assert.True(t, shallow.Integer > 0)
+	assert.Equal(t, shallow.Integer+200, deep.Integer)
+}
@@ -3,6 +3,7 @@ package extension
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
@@ -24,6 +25,34 @@ const TraceKey = "@jiao_trace_id"
 // EncoderConfig warps the `zapcore.EncoderConfig` and carray the trace configration.
 type EncoderConfig struct {
 	TraceKey string `json:"traceKey" yaml:"traceKey"`
+	// TraceFirst puts the trace id column before the timestamp instead of after the caller.
+	TraceFirst bool `json:"traceFirst" yaml:"traceFirst"`
+	// EmitTraceAsField also writes the trace id under TraceKey in the structured context block,
+	// on top of its column, for the consumers only parsing the block.
+	EmitTraceAsField bool `json:"emitTraceAsField" yaml:"emitTraceAsField"`
+	// FieldHints groups the fields wrapped in a HintedField into sections, see HintedField.
+	FieldHints bool `json:"fieldHints" yaml:"fieldHints"`
+	// RedactKeys are the patterns (see path.Match) of the keys whose values are written as RedactedValue,
+	// matched case insensitively. They apply to the string, object, array and reflected fields, including
+	// those of the logged objects, and to the keys of the values encoded by reflection (e.g. zap.Any) at any depth.
+	RedactKeys []string `json:"redactKeys" yaml:"redactKeys"`
+	// FieldPrefix is prepended to the keys of the fields, not to the keys nested in their values.
+	FieldPrefix string `json:"fieldPrefix" yaml:"fieldPrefix"`
+	// MaxReflectedBytes and MaxReflectedDepth replace the values encoded by reflection (e.g. zap.Any)
+	// longer or nested deeper than them by ReflectedTooLargeValue and ReflectedTooDeepValue. Zero means no limit.
+	MaxReflectedBytes int `json:"maxReflectedBytes" yaml:"maxReflectedBytes"`
+	MaxReflectedDepth int `json:"maxReflectedDepth" yaml:"maxReflectedDepth"`
+	// MaxMessageBytes cuts the messages longer than it, on a character boundary, and ends them
+	// with TruncatedSuffix. Zero means no limit.
+	MaxMessageBytes int `json:"maxMessageBytes" yaml:"maxMessageBytes"`
+	// MaxFieldBytes cuts the string and byte string values longer than it, like MaxMessageBytes,
+	// not their keys nor the trace id. Zero means no limit.
+	MaxFieldBytes int `json:"maxFieldBytes" yaml:"maxFieldBytes"`
+	// JSONSeq frames the records of the JSON encoder as json-seq (RFC 7464): each starts with RecordSeparator
+	// and ends with "\n", instead of LineEnding. It doesn't apply to the console encoder.
+	JSONSeq bool `json:"jsonSeq" yaml:"jsonSeq"`
+	// json is set by NewJSONEncoder.
+	json bool
 	zapcore.EncoderConfig
 }
 
@@ -66,6 +95,8 @@ func putConsoleEncoder(enc *consoleEncoder) {
 	enc.EncoderConfig = nil
 	enc.buf = nil
 	enc.openNamespaces = 0
+	enc.objectDepth = 0
+	enc.hinted = nil
 	enc.reflectBuf = nil
 	enc.reflectEnc = nil
 	_consolePool.Put(enc)
@@ -91,7 +122,9 @@ type consoleEncoder struct {
 	buf            *buffer.Buffer
 	spaced         bool
 	openNamespaces int
+	objectDepth    int
 	traceID        string
+	hinted         []HintedField
 
 	// for encoding generic values by reflection
 	reflectBuf *buffer.Buffer
@@ -120,11 +153,19 @@ func NewConsoleEncoder(cfg EncoderConfig) zapcore.Encoder {
 
 func (enc *consoleEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
 	enc.addKey(key)
+	if enc.redacted(key) {
+		enc.buf.Write(redactedLiteralBytes)
+		return nil
+	}
 	return enc.AppendArray(arr)
 }
 
 func (enc *consoleEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
 	enc.addKey(key)
+	if enc.redacted(key) {
+		enc.buf.Write(redactedLiteralBytes)
+		return nil
+	}
 	return enc.AppendObject(obj)
 }
 
@@ -165,12 +206,14 @@ func (enc *consoleEncoder) AddInt64(key string, val int64) {
 func (enc *consoleEncoder) resetReflectBuf() {
 	if enc.reflectBuf == nil {
 		enc.reflectBuf = getBuffer()
-		enc.reflectEnc = json.NewEncoder(enc.reflectBuf)
+	} else {
+		enc.reflectBuf.Reset()
+	}
+	if enc.reflectEnc == nil {
+		enc.reflectEnc = json.NewEncoder(&cappedWriter{buf: enc.reflectBuf, max: enc.MaxReflectedBytes})
 
 		// For consistency with our custom JSON encoder.
 		enc.reflectEnc.SetEscapeHTML(false)
-	} else {
-		enc.reflectBuf.Reset()
 	}
 }
 
@@ -182,15 +225,34 @@ func (enc *consoleEncoder) encodeReflected(obj interface{}) ([]byte, error) {
 	if obj == nil {
 		return nullLiteralBytes, nil
 	}
+	if enc.tooLargeToReflect(obj) {
+		return reflectedTooLargeBytes, nil
+	}
 	enc.resetReflectBuf()
-	if err := enc.reflectEnc.Encode(obj); err != nil {
-		return nil, err
+	err := enc.reflectEnc.Encode(obj)
+	if errors.Is(err, errReflectedTooLarge) {
+		// json.Encoder keeps failing after a write error
+		enc.reflectEnc = nil
 	}
 	enc.reflectBuf.TrimNewline()
+	if marker := enc.guardReflected(enc.reflectBuf.Bytes(), err); marker != nil {
+		return marker, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(enc.RedactKeys) > 0 {
+		return enc.redactJSON(enc.reflectBuf.Bytes())
+	}
 	return enc.reflectBuf.Bytes(), nil
 }
 
 func (enc *consoleEncoder) AddReflected(key string, obj interface{}) error {
+	if enc.redacted(key) {
+		enc.addKey(key)
+		_, err := enc.buf.Write(redactedLiteralBytes)
+		return err
+	}
 	valueBytes, err := enc.encodeReflected(obj)
 	if err != nil {
 		return err
@@ -211,6 +273,9 @@ func (enc *consoleEncoder) AddString(key, val string) {
 	case TraceKey:
 		enc.traceID = val
 	default:
+		if enc.redacted(key) {
+			val = RedactedValue
+		}
 		enc.addKey(key)
 		enc.AppendString(val)
 	}
@@ -235,10 +300,17 @@ func (enc *consoleEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
 }
 
 func (enc *consoleEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	// Close only the namespaces opened by obj, inside its braces.
+	old := enc.openNamespaces
+	enc.openNamespaces = 0
 	enc.addElementSeparator()
 	enc.buf.AppendByte('{')
+	enc.objectDepth++
 	err := obj.MarshalLogObject(enc)
+	enc.objectDepth--
+	enc.closeOpenNamespaces()
 	enc.buf.AppendByte('}')
+	enc.openNamespaces = old
 	return err
 }
 
@@ -250,7 +322,12 @@ func (enc *consoleEncoder) AppendBool(val bool) {
 func (enc *consoleEncoder) AppendByteString(val []byte) {
 	enc.addElementSeparator()
 	enc.buf.AppendByte('"')
-	enc.safeAddByteString(val)
+	if max := enc.MaxFieldBytes; max > 0 && len(val) > max {
+		enc.safeAddByteString(val[:byteCutIndex(val, max)])
+		enc.buf.AppendString(TruncatedSuffix)
+	} else {
+		enc.safeAddByteString(val)
+	}
 	enc.buf.AppendByte('"')
 }
 
@@ -299,7 +376,12 @@ func (enc *consoleEncoder) AppendReflected(val interface{}) error {
 func (enc *consoleEncoder) AppendString(val string) {
 	enc.addElementSeparator()
 	enc.buf.AppendByte('"')
-	enc.safeAddString(val)
+	if max := enc.MaxFieldBytes; max > 0 && len(val) > max {
+		enc.safeAddString(val[:cutIndex(val, max)])
+		enc.buf.AppendString(TruncatedSuffix)
+	} else {
+		enc.safeAddString(val)
+	}
 	enc.buf.AppendByte('"')
 }
 
@@ -355,18 +437,63 @@ func (enc *consoleEncoder) clone() *consoleEncoder {
 	clone.EncoderConfig = enc.EncoderConfig
 	clone.openNamespaces = enc.openNamespaces
 	clone.traceID = enc.traceID
+	clone.hinted = append([]HintedField(nil), enc.hinted...)
 	clone.buf = getBuffer()
 	return clone
 }
 
+// TruncatedSuffix ends the messages and values cut by EncoderConfig.MaxMessageBytes and MaxFieldBytes.
+const TruncatedSuffix = "...(truncated)"
+
+// appendMessage appends msg to line, cut to MaxMessageBytes without splitting a multi-byte character.
+func (cfg *EncoderConfig) appendMessage(line *buffer.Buffer, msg string) {
+	if cfg.MaxMessageBytes <= 0 || len(msg) <= cfg.MaxMessageBytes {
+		line.AppendString(msg)
+		return
+	}
+	line.AppendString(msg[:cutIndex(msg, cfg.MaxMessageBytes)])
+	line.AppendString(TruncatedSuffix)
+}
+
+// cutIndex returns the index of the first character of s ending after max bytes, max being less than len(s).
+func cutIndex(s string, max int) int {
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return max
+}
+
+// byteCutIndex is cutIndex for a byte string.
+func byteCutIndex(s []byte, max int) int {
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return max
+}
+
 // EncodeEntry encodes an entry and fields, along with any accumulated
 // context, into a byte buffer and returns it. Any fields that are empty,
 // including fields on the `Entry` type, should be omitted.
 func (enc *consoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
-	final := enc.clone()
+	var final *consoleEncoder
+	if enc.openNamespaces == 0 && enc.buf.Len() == 0 && len(enc.hinted) == 0 {
+		// Fast path: there's no accumulated context to carry over, so
+		// the fields are written into a bare pooled encoder.
+		final = getConsoleEncoder()
+		final.EncoderConfig = enc.EncoderConfig
+		final.traceID = enc.traceID
+		final.buf = getBuffer()
+	} else {
+		final = enc.clone()
+	}
 	defer func() {
+		// The scratch buffer never escapes: writeContext copies it into line.
+		final.buf.Free()
 		putConsoleEncoder(final)
 	}()
+	if final.json {
+		return enc.encodeJSONEntry(final, ent, fields), nil
+	}
 	line := getBuffer()
 
 	// We don't want the entry's metadata to be quoted and escaped (if it's
@@ -376,6 +503,9 @@ func (enc *consoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field
 	// If this ever becomes a performance bottleneck, we can implement
 	// ArrayEncoder for our plain-text format.
 	arr := getSliceEncoder()
+	if final.TraceFirst {
+		arr.AppendString(final.traceID)
+	}
 	if final.TimeKey != "" && final.EncodeTime != nil {
 		final.EncodeTime(ent.Time, arr)
 	}
@@ -398,10 +528,12 @@ func (enc *consoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field
 		}
 	}
 	// Add the trace id.
-	if final.traceID != "" {
-		arr.AppendString(final.traceID)
-	} else {
-		arr.AppendString("")
+	if !final.TraceFirst {
+		if final.traceID != "" {
+			arr.AppendString(final.traceID)
+		} else {
+			arr.AppendString("")
+		}
 	}
 	for i := range arr.elems {
 		if i > 0 {
@@ -414,7 +546,7 @@ func (enc *consoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field
 	// Add the message itself.
 	if final.MessageKey != "" {
 		final.addSeparatorIfNecessary(line)
-		line.AppendString(ent.Message)
+		final.appendMessage(line, ent.Message)
 	}
 
 	if enc.buf.Len() > 0 {
@@ -449,6 +581,9 @@ func (enc *consoleEncoder) closeOpenNamespaces() {
 func (enc *consoleEncoder) addKey(key string) {
 	enc.addElementSeparator()
 	enc.buf.AppendByte('"')
+	if enc.FieldPrefix != "" && enc.openNamespaces == 0 && enc.objectDepth == 0 {
+		enc.safeAddString(enc.FieldPrefix)
+	}
 	enc.safeAddString(key)
 	enc.buf.AppendByte('"')
 	enc.buf.AppendByte(':')
@@ -569,18 +704,43 @@ func addFields(enc zapcore.ObjectEncoder, fields []zapcore.Field) {
 }
 
 func (enc *consoleEncoder) writeContext(line *buffer.Buffer, extra []zapcore.Field) {
+	// The trace id column is already written, the field must match it.
+	traceID := enc.traceID
 	addFields(enc, extra)
 	enc.closeOpenNamespaces()
-	if enc.buf.Len() == 0 {
+	enc.addHintSections()
+	withTrace := enc.EmitTraceAsField && traceID != ""
+	if enc.buf.Len() == 0 && !withTrace {
 		return
 	}
 
 	enc.addSeparatorIfNecessary(line)
 	line.AppendByte('{')
+	if withTrace {
+		enc.writeTraceField(line, traceID)
+		if enc.buf.Len() > 0 {
+			line.AppendByte(',')
+		}
+	}
 	line.Write(enc.buf.Bytes())
 	line.AppendByte('}')
 }
 
+// writeTraceField writes the trace id into line as a field, see EncoderConfig.EmitTraceAsField.
+func (enc *consoleEncoder) writeTraceField(line *buffer.Buffer, traceID string) {
+	buf := enc.buf
+	enc.buf = line
+	line.AppendByte('"')
+	enc.safeAddString(TraceKey)
+	line.AppendByte('"')
+	line.AppendByte(':')
+	// not enc.AppendString, the trace id is never cut by MaxFieldBytes
+	line.AppendByte('"')
+	enc.safeAddString(traceID)
+	line.AppendByte('"')
+	enc.buf = buf
+}
+
 func (enc *consoleEncoder) addSeparatorIfNecessary(line *buffer.Buffer) {
 	if line.Len() > 0 {
 		line.AppendString(enc.ConsoleSeparator)
@@ -0,0 +1,314 @@
+package extension
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestEncoderConfig() EncoderConfig {
+	cfg := NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05")
+	cfg.ConsoleSeparator = "|"
+	return cfg
+}
+
+func encodeTestEntry(t *testing.T, cfg EncoderConfig, fields ...zapcore.Field) string {
+	enc := NewConsoleEncoder(cfg)
+	enc.AddString(TraceKey, "trace-id")
+	ent := zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Message: "hello",
+	}
+	buf, err := enc.EncodeEntry(ent, fields)
+	assert.Nil(t, err)
+	return buf.String()
+}
+
+func TestEncodeEntryTraceTrailing(t *testing.T) {
+	line := encodeTestEntry(t, newTestEncoderConfig(), zap.Int("a", 1))
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|{\"a\":1}\n", line)
+}
+
+func TestEncodeEntryTraceFirst(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.TraceFirst = true
+	line := encodeTestEntry(t, cfg, zap.Int("a", 1))
+	assert.True(t, strings.HasPrefix(line, "trace-id|"))
+	assert.Equal(t, "trace-id|2024-06-01 00:00:00|info|hello|{\"a\":1}\n", line)
+}
+
+func TestEmitTraceAsField(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.EmitTraceAsField = true
+	line := encodeTestEntry(t, cfg, zap.Int("a", 1))
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|{\"@jiao_trace_id\":\"trace-id\",\"a\":1}\n", line)
+	line = encodeTestEntry(t, cfg)
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|{\"@jiao_trace_id\":\"trace-id\"}\n", line)
+
+	// the field matches the column
+	line = encodeTestEntry(t, cfg, zap.String(TraceKey, "entry-trace-id"))
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|{\"@jiao_trace_id\":\"trace-id\"}\n", line)
+
+	// no trace id
+	enc := NewConsoleEncoder(cfg)
+	buf, err := enc.EncodeEntry(zapcore.Entry{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Message: "hello"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-06-01 00:00:00|info||hello\n", buf.String())
+}
+
+func TestMaxMessageBytes(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.MaxMessageBytes = 4
+	enc := NewConsoleEncoder(cfg)
+	encode := func(msg string) string {
+		buf, err := enc.EncodeEntry(zapcore.Entry{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Message: msg}, nil)
+		assert.Nil(t, err)
+		return strings.TrimPrefix(buf.String(), "2024-06-01 00:00:00|info||")
+	}
+
+	assert.Equal(t, "abcd\n", encode("abcd"))
+	assert.Equal(t, "abcd...(truncated)\n", encode("abcdefgh"))
+	// "é" takes 2 bytes, so the 4th byte is in the middle of the second one
+	assert.Equal(t, "aé...(truncated)\n", encode("aééé"))
+	assert.Equal(t, "a...(truncated)\n", encode("a\U0001F600"))
+}
+
+func TestMaxFieldBytes(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.MaxFieldBytes = 8
+	cfg.EmitTraceAsField = true
+	long := strings.Repeat("x", 1<<20)
+	line := encodeTestEntry(t, cfg, zap.String("body", long), zap.ByteString("raw", []byte(long)),
+		zap.Strings("list", []string{"short", "é" + long}), zap.String("a_key_longer_than_the_limit", "ok"))
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|{\"@jiao_trace_id\":\"trace-id\","+
+		"\"body\":\"xxxxxxxx...(truncated)\",\"raw\":\"xxxxxxxx...(truncated)\","+
+		"\"list\":[\"short\",\"éxxxxxx...(truncated)\"],\"a_key_longer_than_the_limit\":\"ok\"}\n", line)
+	assert.True(t, len(line) < 300, len(line))
+}
+
+func TestEncodeEntryWithContext(t *testing.T) {
+	ent := zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Message: "hello",
+	}
+	enc := NewConsoleEncoder(newTestEncoderConfig())
+	enc.AddString(TraceKey, "trace-id")
+	withContext := enc.Clone()
+	withContext.AddInt("ctx", 1)
+	withNamespace := enc.Clone()
+	withNamespace.OpenNamespace("ns")
+
+	for i := 0; i < 2; i++ {
+		buf, err := enc.EncodeEntry(ent, []zapcore.Field{zap.Int("a", 1)})
+		assert.Nil(t, err)
+		assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|{\"a\":1}\n", buf.String())
+		buf, err = enc.EncodeEntry(ent, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello\n", buf.String())
+		buf, err = withContext.EncodeEntry(ent, []zapcore.Field{zap.Int("a", 1)})
+		assert.Nil(t, err)
+		assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|{\"ctx\":1,\"a\":1}\n", buf.String())
+		buf, err = withNamespace.EncodeEntry(ent, []zapcore.Field{zap.Int("a", 1)})
+		assert.Nil(t, err)
+		assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|{\"ns\":{\"a\":1}}\n", buf.String())
+	}
+}
+
+func TestEncodeEntryNamespaceInObject(t *testing.T) {
+	obj := zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddInt("a", 1)
+		enc.OpenNamespace("inner")
+		enc.AddInt("b", 2)
+		enc.OpenNamespace("deeper")
+		enc.AddInt("c", 3)
+		return nil
+	})
+	line := encodeTestEntry(t, newTestEncoderConfig(), zap.Object("obj", obj), zap.Int("after", 4),
+		zap.Namespace("ns"), zap.Object("nested", obj), zap.Int("last", 5))
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|"+
+		`{"obj":{"a":1,"inner":{"b":2,"deeper":{"c":3}}},"after":4,`+
+		`"ns":{"nested":{"a":1,"inner":{"b":2,"deeper":{"c":3}}},"last":5}}`+"\n", line)
+	block := line[strings.Index(line, "|{")+1 : len(line)-1]
+	assert.True(t, json.Valid([]byte(block)), block)
+}
+
+func BenchmarkEncodeEntry(b *testing.B) {
+	enc := NewConsoleEncoder(newTestEncoderConfig())
+	enc.AddString(TraceKey, "trace-id")
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "hello"}
+	fields := []zapcore.Field{zap.Int("a", 1), zap.String("b", "x")}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ := enc.EncodeEntry(ent, fields)
+		buf.Free()
+	}
+}
+
+func BenchmarkEncodeEntryWithContext(b *testing.B) {
+	enc := NewConsoleEncoder(newTestEncoderConfig())
+	enc.AddString(TraceKey, "trace-id")
+	enc.AddInt("ctx", 1)
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "hello"}
+	fields := []zapcore.Field{zap.Int("a", 1), zap.String("b", "x")}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ := enc.EncodeEntry(ent, fields)
+		buf.Free()
+	}
+}
+
+func TestNumericLevelEncoder(t *testing.T) {
+	codes := map[zapcore.Level]int{
+		zapcore.DebugLevel:  10,
+		zapcore.InfoLevel:   20,
+		zapcore.WarnLevel:   30,
+		zapcore.ErrorLevel:  40,
+		zapcore.DPanicLevel: 50,
+		zapcore.PanicLevel:  60,
+		zapcore.FatalLevel:  70,
+	}
+	for lvl, code := range codes {
+		assert.Equal(t, code, LevelCode(lvl), lvl.String())
+	}
+
+	cfg := newTestEncoderConfig()
+	cfg.EncodeLevel = NumericLevelEncoder
+	line := encodeTestEntry(t, cfg)
+	assert.Equal(t, "2024-06-01 00:00:00|20|trace-id|hello\n", line)
+}
+
+func TestJSONEncoder(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.FieldPrefix = "f_"
+	cfg.MaxMessageBytes = 8
+	cfg.RedactKeys = []string{"password"}
+	enc := NewJSONEncoder(cfg).Clone()
+	enc.AddString(TraceKey, "trace-id")
+	enc.AddInt("ctx", 1)
+	ent := zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Message: "hello\nworld",
+		Caller:  zapcore.NewEntryCaller(0, "/src/app/main.go", 7, true),
+	}
+	buf, err := enc.EncodeEntry(ent, []zapcore.Field{zap.Int("a", 1), zap.String("password", "secret")})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ts":"2024-06-01 00:00:00","level":"info","caller":"app/main.go:7","@jiao_trace_id":"trace-id",`+
+		`"msg":"hello\nwo...(truncated)","f_ctx":1,"f_a":1,"f_password":"***"}`+"\n", buf.String())
+	assert.True(t, json.Valid(buf.Bytes()))
+
+	buf, err = NewJSONEncoder(newTestEncoderConfig()).EncodeEntry(zapcore.Entry{Time: ent.Time, Message: "m"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ts":"2024-06-01 00:00:00","level":"info","msg":"m"}`+"\n", buf.String())
+}
+
+func TestJSONSeq(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.JSONSeq = true
+	cfg.LineEnding = "\r\n"
+	enc := NewJSONEncoder(cfg)
+	enc.AddString(TraceKey, "trace-id")
+	ent := zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Message: "a\nb"}
+
+	var stream []byte
+	for i := 0; i < 3; i++ {
+		buf, err := enc.EncodeEntry(ent, []zapcore.Field{zap.Int("i", i)})
+		assert.Nil(t, err)
+		stream = append(stream, buf.Bytes()...)
+	}
+	records := strings.Split(string(stream), "\x1e")
+	assert.Equal(t, "", records[0])
+	assert.Len(t, records, 4)
+	for i, record := range records[1:] {
+		assert.True(t, strings.HasSuffix(record, "}\n"), record)
+		var decoded map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(record), &decoded), record)
+		assert.Equal(t, "a\nb", decoded["msg"])
+		assert.Equal(t, float64(i), decoded["i"])
+	}
+
+	// the console encoder isn't framed
+	line := encodeTestEntry(t, cfg)
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello\r\n", line)
+}
+
+type countingMarshaler struct{ calls *int }
+
+func (m countingMarshaler) MarshalJSON() ([]byte, error) {
+	*m.calls++
+	return []byte("1"), nil
+}
+
+func TestMaxReflectedBytesAbortsEarly(t *testing.T) {
+	cfg := newTestEncoderConfig()
+	cfg.MaxReflectedBytes = 64
+	calls := 0
+	huge := make([]countingMarshaler, 1<<20)
+	for i := range huge {
+		huge[i] = countingMarshaler{calls: &calls}
+	}
+	line := encodeTestEntry(t, cfg, zap.Any("huge", huge), zap.Any("long", struct{ S string }{strings.Repeat("x", 1<<20)}),
+		zap.Any("small", huge[:3]))
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|"+
+		"{\"huge\":\"...(too large)\",\"long\":\"...(too large)\",\"small\":[1,1,1]}\n", line)
+	assert.Equal(t, 3, calls)
+
+	// the values under the lower bound are rejected by the writer of their encoding
+	line = encodeTestEntry(t, cfg, zap.Any("opaque", huge[:40]), zap.Any("fits", huge[:20]))
+	assert.Equal(t, "2024-06-01 00:00:00|info|trace-id|hello|"+
+		"{\"opaque\":\"...(too large)\",\"fits\":["+strings.Repeat("1,", 19)+"1]}\n", line)
+	assert.Equal(t, 63, calls)
+}
+
+func TestMinReflectedSize(t *testing.T) {
+	type inner struct {
+		Name  string `json:"name"`
+		Empty string `json:"empty,omitempty"`
+		Skip  int    `json:"-"`
+		Dash  int    `json:"-,"`
+		priv  int
+	}
+	type embedded struct{ Promoted int }
+	type outer struct {
+		embedded
+		Inner  inner             `json:"inner"`
+		Ptr    *inner            `json:"ptr"`
+		List   []int             `json:"list"`
+		Nil    []int             `json:"nil"`
+		Map    map[string]string `json:"map"`
+		IntMap map[int]bool      `json:"int_map"`
+		Any    interface{}       `json:"any"`
+		Time   time.Time         `json:"time"`
+		Number json.Number       `json:"number"`
+		Bytes  []byte            `json:"bytes"`
+		Array  [2]string         `json:"array"`
+	}
+	values := []interface{}{
+		nil, true, 1.5, "é\n<", []int{}, map[string]int{}, struct{}{}, inner{priv: 1},
+		outer{
+			embedded: embedded{Promoted: 1},
+			Inner:    inner{Name: "a", Skip: 1},
+			List:     []int{1, 22, 333},
+			Map:      map[string]string{"k": "v", "kk": "vv"},
+			IntMap:   map[int]bool{10: true},
+			Any:      []interface{}{"x", nil},
+			Time:     time.Now(),
+			Number:   "12",
+			Bytes:    []byte("bytes"),
+		},
+	}
+	for _, v := range values {
+		data, err := json.Marshal(v)
+		assert.Nil(t, err)
+		assert.True(t, minReflectedSize(reflect.ValueOf(v), 1<<20, maxSizeWalkDepth) <= len(data), string(data))
+	}
+}
@@ -0,0 +1,67 @@
+package extension
+
+import "go.uber.org/zap/zapcore"
+
+// FieldHint tells the log backend how to store a field.
+type FieldHint uint8
+
+const (
+	// HintIndexed marks a field searchable by the log backend.
+	HintIndexed FieldHint = iota + 1
+	// HintStored marks a field only retrievable with its log, not searchable.
+	HintStored
+)
+
+// String returns the key of the section of the hinted fields.
+func (h FieldHint) String() string {
+	switch h {
+	case HintIndexed:
+		return "indexed"
+	case HintStored:
+		return "stored"
+	default:
+		return ""
+	}
+}
+
+// HintedField wraps a field with a FieldHint, it must be logged with zap.Inline.
+//
+// It is encoded like the wrapped field, unless EncoderConfig.FieldHints is set:
+// the console encoder then groups the top level hinted fields into one object
+// per hint, e.g. "indexed":{...},"stored":{...}, after the other fields.
+type HintedField struct {
+	Hint  FieldHint
+	Field zapcore.Field
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (f HintedField) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if ce, ok := enc.(*consoleEncoder); ok && ce.FieldHints && ce.openNamespaces == 0 && ce.objectDepth == 0 {
+		ce.hinted = append(ce.hinted, f)
+		return nil
+	}
+	f.Field.AddTo(enc)
+	return nil
+}
+
+type hintSection []zapcore.Field
+
+func (s hintSection) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	addFields(enc, s)
+	return nil
+}
+
+// addHintSections adds the hinted fields collected by HintedField, one object per hint.
+func (enc *consoleEncoder) addHintSections() {
+	for _, hint := range []FieldHint{HintIndexed, HintStored} {
+		var section hintSection
+		for _, f := range enc.hinted {
+			if f.Hint == hint {
+				section = append(section, f.Field)
+			}
+		}
+		if len(section) > 0 {
+			_ = enc.AddObject(hint.String(), section)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package extension
+
+import (
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// RecordSeparator is the ASCII RS byte starting each record framed by EncoderConfig.JSONSeq.
+const RecordSeparator = 0x1E
+
+// NewJSONEncoder creates an encoder writing each entry as a JSON object, for the machines, with its
+// metadata (time, level, caller, message, etc.) under the keys of cfg and the trace id under TraceKey,
+// followed by the structured context encoded like by the console encoder (redacted, cut, hinted, etc.).
+//
+// The options of the console columns, ConsoleSeparator, TraceFirst and EmitTraceAsField, don't apply.
+func NewJSONEncoder(cfg EncoderConfig) zapcore.Encoder {
+	cfg.json = true
+	return NewConsoleEncoder(cfg)
+}
+
+// IsJSONEncoder reports whether enc is an encoder created by NewJSONEncoder, for the values written
+// in a human readable form by the console encoder but as raw numbers for the machines.
+func IsJSONEncoder(enc zapcore.ObjectEncoder) bool {
+	ce, ok := enc.(*consoleEncoder)
+	return ok && ce.json
+}
+
+// encodeJSONEntry is EncodeEntry for the encoders created by NewJSONEncoder, final holding the context of enc.
+func (enc *consoleEncoder) encodeJSONEntry(final *consoleEncoder, ent zapcore.Entry, fields []zapcore.Field) *buffer.Buffer {
+	// The context comes first, so a trace id among fields is the one of the entry.
+	if enc.buf.Len() > 0 {
+		final.addElementSeparator()
+		final.buf.Write(enc.buf.Bytes())
+	}
+	addFields(final, fields)
+	final.closeOpenNamespaces()
+	final.addHintSections()
+	context := final.buf
+
+	line := getBuffer()
+	if final.JSONSeq {
+		line.AppendByte(RecordSeparator)
+	}
+	line.AppendByte('{')
+	final.buf = line
+	if final.TimeKey != "" {
+		final.addEntryKey(final.TimeKey)
+		final.AppendTime(ent.Time)
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		final.addEntryKey(final.LevelKey)
+		final.EncodeLevel(ent.Level, final)
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		nameEncoder := final.EncodeName
+		if nameEncoder == nil {
+			nameEncoder = zapcore.FullNameEncoder
+		}
+		final.addEntryKey(final.NameKey)
+		nameEncoder(ent.LoggerName, final)
+	}
+	if ent.Caller.Defined && final.CallerKey != "" && final.EncodeCaller != nil {
+		final.addEntryKey(final.CallerKey)
+		final.EncodeCaller(ent.Caller, final)
+	}
+	if final.traceID != "" {
+		final.addEntryString(TraceKey, final.traceID)
+	}
+	if final.MessageKey != "" {
+		msg := ent.Message
+		if final.MaxMessageBytes > 0 && len(msg) > final.MaxMessageBytes {
+			msg = msg[:cutIndex(msg, final.MaxMessageBytes)] + TruncatedSuffix
+		}
+		final.addEntryString(final.MessageKey, msg)
+	}
+	if context.Len() > 0 {
+		final.addElementSeparator()
+		line.Write(context.Bytes())
+	}
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.addEntryString(final.StacktraceKey, ent.Stack)
+	}
+	line.AppendByte('}')
+	final.buf = context
+
+	switch {
+	case final.JSONSeq:
+		// RFC 7464 requires a line feed to end each record.
+		line.AppendByte('\n')
+	case final.LineEnding != "":
+		line.AppendString(final.LineEnding)
+	default:
+		line.AppendString(zapcore.DefaultLineEnding)
+	}
+	return line
+}
+
+// addEntryKey adds the key of a metadata of the entry, never prefixed by FieldPrefix unlike the field keys.
+func (enc *consoleEncoder) addEntryKey(key string) {
+	enc.addElementSeparator()
+	enc.buf.AppendByte('"')
+	enc.safeAddString(key)
+	enc.buf.AppendString(`":`)
+}
+
+// addEntryString adds a metadata of the entry which is never cut by MaxFieldBytes, e.g. the message.
+func (enc *consoleEncoder) addEntryString(key, val string) {
+	enc.addEntryKey(key)
+	enc.buf.AppendByte('"')
+	enc.safeAddString(val)
+	enc.buf.AppendByte('"')
+}
@@ -0,0 +1,25 @@
+package extension
+
+import "go.uber.org/zap/zapcore"
+
+// levelCodes maps each level to the numeric code indexed by some log analyzers.
+var levelCodes = map[zapcore.Level]int{
+	zapcore.DebugLevel:  10,
+	zapcore.InfoLevel:   20,
+	zapcore.WarnLevel:   30,
+	zapcore.ErrorLevel:  40,
+	zapcore.DPanicLevel: 50,
+	zapcore.PanicLevel:  60,
+	zapcore.FatalLevel:  70,
+}
+
+// LevelCode returns the numeric code of the level: debug=10, info=20, warn=30, error=40,
+// dpanic=50, panic=60, fatal=70. Unknown levels are 0.
+func LevelCode(l zapcore.Level) int {
+	return levelCodes[l]
+}
+
+// NumericLevelEncoder serializes a Level to its numeric code, see LevelCode.
+func NumericLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt(LevelCode(l))
+}
@@ -0,0 +1,83 @@
+package extension
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// RedactedValue replaces the values of the keys matching EncoderConfig.RedactKeys.
+const RedactedValue = "***"
+
+var redactedLiteralBytes = []byte(`"` + RedactedValue + `"`)
+
+// redacted returns true if key matches one of the RedactKeys patterns, case insensitively.
+func (cfg *EncoderConfig) redacted(key string) bool {
+	if len(cfg.RedactKeys) == 0 {
+		return false
+	}
+	key = strings.ToLower(key)
+	for _, pattern := range cfg.RedactKeys {
+		if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactJSON returns data with the values of the object keys matching RedactKeys replaced by RedactedValue,
+// at any depth. The order of the keys is kept.
+func (cfg *EncoderConfig) redactJSON(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || (data[0] != '{' && data[0] != '[') {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.WriteByte(data[0])
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		redacted := false
+		if data[0] == '{' {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := tok.(string)
+			keyBytes, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(keyBytes)
+			out.WriteByte(':')
+			redacted = cfg.redacted(key)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if redacted {
+			out.Write(redactedLiteralBytes)
+			continue
+		}
+		value, err := cfg.redactJSON(value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(value)
+	}
+	if data[0] == '{' {
+		out.WriteByte('}')
+	} else {
+		out.WriteByte(']')
+	}
+	return out.Bytes(), nil
+}
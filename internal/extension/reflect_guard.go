@@ -0,0 +1,196 @@
+package extension
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+)
+
+const (
+	// ReflectedTooLargeValue replaces the values encoded by reflection longer than EncoderConfig.MaxReflectedBytes.
+	ReflectedTooLargeValue = "...(too large)"
+	// ReflectedTooDeepValue replaces the values encoded by reflection nested deeper than
+	// EncoderConfig.MaxReflectedDepth, and the cyclic values when it is set.
+	ReflectedTooDeepValue = "...(too deep)"
+)
+
+// maxSizeWalkDepth bounds the nesting walked by minReflectedSize without MaxReflectedDepth, e.g. for the cycles.
+const maxSizeWalkDepth = 64
+
+var (
+	reflectedTooLargeBytes = []byte(`"` + ReflectedTooLargeValue + `"`)
+	reflectedTooDeepBytes  = []byte(`"` + ReflectedTooDeepValue + `"`)
+
+	errReflectedTooLarge = errors.New("reflected value too large")
+	newline              = []byte("\n")
+
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// cappedWriter fails with errReflectedTooLarge the writes making buf longer than max bytes, which are not copied
+// into it. Zero max means no limit.
+type cappedWriter struct {
+	buf *buffer.Buffer
+	max int
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	// json.Encoder ends the value with a newline, which is trimmed
+	if w.max > 0 && w.buf.Len()+len(bytes.TrimSuffix(p, newline)) > w.max {
+		return 0, errReflectedTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+// guardReflected returns the marker replacing the encoded value data, or the error err of its encoding,
+// if it breaks the MaxReflectedBytes or MaxReflectedDepth limit, and nil otherwise.
+func (cfg *EncoderConfig) guardReflected(data []byte, err error) []byte {
+	if err != nil {
+		var unsupported *json.UnsupportedValueError
+		if cfg.MaxReflectedDepth > 0 && errors.As(err, &unsupported) &&
+			strings.HasPrefix(unsupported.Str, "encountered a cycle") {
+			return reflectedTooDeepBytes
+		}
+		if errors.Is(err, errReflectedTooLarge) {
+			return reflectedTooLargeBytes
+		}
+		return nil
+	}
+	if cfg.MaxReflectedDepth > 0 && jsonDepth(data) > cfg.MaxReflectedDepth {
+		return reflectedTooDeepBytes
+	}
+	return nil
+}
+
+// tooLargeToReflect reports whether obj is sure to be encoded longer than MaxReflectedBytes,
+// so the values far too large, e.g. huge slices, are rejected without being marshalled.
+func (cfg *EncoderConfig) tooLargeToReflect(obj interface{}) bool {
+	if cfg.MaxReflectedBytes <= 0 {
+		return false
+	}
+	maxDepth := maxSizeWalkDepth
+	if cfg.MaxReflectedDepth > 0 {
+		maxDepth = cfg.MaxReflectedDepth
+	}
+	return minReflectedSize(reflect.ValueOf(obj), cfg.MaxReflectedBytes, maxDepth) > cfg.MaxReflectedBytes
+}
+
+// minReflectedSize returns a lower bound of the length of v encoded by encoding/json, returning as soon as
+// it is over max. The values with their own marshaler and those nested deeper than maxDepth count as empty.
+func minReflectedSize(v reflect.Value, max, maxDepth int) int {
+	if !v.IsValid() {
+		return len(nullLiteralBytes)
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return len("true")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return 1
+	case reflect.String:
+		// json.Number is written without the quotes, the escaping only adds bytes
+		return v.Len()
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return len(nullLiteralBytes)
+		}
+		return minReflectedSize(v.Elem(), max, maxDepth)
+	}
+	if maxDepth <= 0 {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return len(nullLiteralBytes)
+		}
+		size := 2 + commas(v.Len())
+		for i := 0; i < v.Len() && size <= max; i++ {
+			size += minReflectedSize(v.Index(i), max-size, maxDepth-1)
+		}
+		return size
+	case reflect.Map:
+		if v.IsNil() {
+			return len(nullLiteralBytes)
+		}
+		// the quoted keys and colons, then the commas
+		size := 2 + v.Len()*3 + commas(v.Len())
+		for iter := v.MapRange(); iter.Next() && size <= max; {
+			if iter.Key().Kind() == reflect.String {
+				size += iter.Key().Len()
+			}
+			size += minReflectedSize(iter.Value(), max-size, maxDepth-1)
+		}
+		return size
+	case reflect.Struct:
+		size := 1
+		for i := 0; i < t.NumField() && size <= max; i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			// the embedded fields may be promoted or dropped, and the empty ones omitted
+			if !f.IsExported() || f.Anonymous || name == "-" && opts == "" || strings.Contains(opts, "omit") {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			// the quoted name, colon and comma, or closing brace
+			size += len(name) + 4 + minReflectedSize(v.Field(i), max-size, maxDepth-1)
+		}
+		if size == 1 {
+			size++
+		}
+		return size
+	default:
+		// not encodable, encoding/json fails
+		return 0
+	}
+}
+
+// commas returns the number of commas separating n elements.
+func commas(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return n - 1
+}
+
+// jsonDepth returns the nesting depth of the objects and arrays of the valid JSON data, 0 for a scalar.
+func jsonDepth(data []byte) int {
+	depth, max := 0, 0
+	inString, escaped := false, false
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			if depth > max {
+				max = depth
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return max
+}
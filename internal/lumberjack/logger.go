@@ -39,8 +39,11 @@ import (
 
 const (
 	backupTimeFormat = "2006-01-02T15-04-05.000"
-	compressSuffix   = ".gz"
-	defaultMaxSize   = 100
+	hourBucketFormat = "2006010215"
+	// minuteBucketFormat is used by the intervals which are not a whole number of hours.
+	minuteBucketFormat = "200601021504"
+	compressSuffix     = ".gz"
+	defaultMaxSize     = 100
 )
 
 // ensure we always implement io.WriteCloser
@@ -109,13 +112,41 @@ type Logger struct {
 	// using gzip. The default is not to perform compression.
 	Compress bool `json:"compress" yaml:"compress"`
 
+	// CompressionLevel is the gzip level used to compress rotated log files,
+	// from gzip.BestSpeed to gzip.BestCompression, or gzip.HuffmanOnly.
+	// The default (0) is gzip.DefaultCompression, not gzip.NoCompression.
+	CompressionLevel int `json:"compressionlevel" yaml:"compressionlevel"`
+
+	// CopyTruncate determines if the log file is rotated by copying it to the
+	// backup file and truncating it in place, instead of renaming it. The log
+	// file then keeps its inode, which is safer on network filesystems. Lines
+	// written by other processes between the copy and the truncate are lost.
+	CopyTruncate bool `json:"copytruncate" yaml:"copytruncate"`
+
 	BufferSize int `json:"buffersize" yaml:"buffersize"`
 
+	// FlushInterval is how often the buffered writes are flushed to the file.
+	// The default wrapper is used if it is not positive.
+	FlushInterval time.Duration `json:"flushinterval" yaml:"flushinterval"`
+
+	// Unbuffered writes each write into the file right away, instead of
+	// buffering them, FlushInterval and BufferSize are then ignored.
+	Unbuffered bool `json:"unbuffered" yaml:"unbuffered"`
+
+	// RotateInterval splits the logs into one file per interval when positive,
+	// e.g. `server-2024060114.log` for the 14:00 hour with time.Hour. The
+	// buffered writes are flushed into the file of an interval before moving to
+	// the next one. Files still rotate within an interval when exceeding MaxSize.
+	// Closed interval files are backups, for MaxBackups, MaxAge and Compress.
+	RotateInterval time.Duration `json:"rotateinterval" yaml:"rotateinterval"`
+
 	size    int64
+	bucket  time.Time
 	file    *os.File
 	mu      sync.Mutex
 	writer  writer.BufferedWriter
 	wrapper WriterWrapper
+	stats   writer.Stats
 
 	millCh    chan bool
 	startMill sync.Once
@@ -149,6 +180,13 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 		)
 	}
 
+	if l.file != nil && l.RotateInterval > 0 && !l.now().Before(l.bucket.Add(l.RotateInterval)) {
+		// flush the current interval into its file before moving to the next one
+		if err = l.close(); err != nil {
+			return 0, err
+		}
+	}
+
 	if l.file == nil {
 		if err = l.openExistingOrNew(len(p)); err != nil {
 			return 0, err
@@ -188,11 +226,24 @@ func (l *Logger) close() error {
 	if err != nil {
 		return err
 	}
+	l.stats = l.stats.Add(l.writer.Stats())
+	l.writer = nil
 	err = l.file.Close()
 	l.file = nil
 	return err
 }
 
+// Stats returns the counters of the buffered writers used by the Logger,
+// accumulated over all the files it has written to.
+func (l *Logger) Stats() writer.Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.writer == nil {
+		return l.stats
+	}
+	return l.stats.Add(l.writer.Stats())
+}
+
 // Rotate causes Logger to close the existing log file and immediately create a
 // new one.  This is a helper function for applications that want to initiate
 // rotations outside of the normal rotation rules, such as in response to
@@ -221,6 +272,7 @@ func (l *Logger) rotate() error {
 // openNew opens a new log file for writing, moving any old log file out of the
 // way.  This methods assumes the file has already been closed.
 func (l *Logger) openNew() error {
+	l.setBucket()
 	err := os.MkdirAll(l.dir(), 0755)
 	if err != nil {
 		return fmt.Errorf("can't make directories for new logfile: %s", err)
@@ -232,15 +284,22 @@ func (l *Logger) openNew() error {
 	if err == nil {
 		// Copy the mode off the old logfile.
 		mode = info.Mode()
-		// move the existing file
 		newname := backupName(name, l.LocalTime)
-		if err := os.Rename(name, newname); err != nil {
-			return fmt.Errorf("can't rename log file: %s", err)
-		}
+		if l.CopyTruncate {
+			// copy the existing file, it is truncated below
+			if err := copyFile(name, newname, mode); err != nil {
+				return fmt.Errorf("can't copy log file: %s", err)
+			}
+		} else {
+			// move the existing file
+			if err := os.Rename(name, newname); err != nil {
+				return fmt.Errorf("can't rename log file: %s", err)
+			}
 
-		// this is a no-op anywhere but linux
-		if err := chown(name, info); err != nil {
-			return err
+			// this is a no-op anywhere but linux
+			if err := chown(name, info); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -252,15 +311,48 @@ func (l *Logger) openNew() error {
 		return fmt.Errorf("can't open new logfile: %s", err)
 	}
 	l.file = f
-	if l.wrapper == nil {
-		l.wrapper = defaultWriterWrapper
-	}
-	l.writer = l.wrapper(f)
+	l.writer = l.newWriter(f)
 
 	l.size = 0
 	return nil
 }
 
+// copyFile copies the content of src into a new file dst created with the given mode.
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// newWriter wraps the file with the writer wrapper of the logger.
+func (l *Logger) newWriter(f *os.File) writer.BufferedWriter {
+	if l.wrapper == nil {
+		l.wrapper = defaultWriterWrapper
+		if l.Unbuffered {
+			l.wrapper = writer.NewDirectWriter
+		} else if l.FlushInterval > 0 {
+			size := l.BufferSize
+			if size <= 0 {
+				size = defaultBufferSize
+			}
+			l.wrapper = doubleBufWrapper(size, l.FlushInterval)
+		}
+	}
+	return l.wrapper(f)
+}
+
 // backupName creates a new filename from the given name, inserting a timestamp
 // between the filename and the extension, using the local time if requested
 // (otherwise UTC).
@@ -282,6 +374,7 @@ func backupName(name string, local bool) string {
 // would not put it over MaxSize.  If there is no such file or the write would
 // put it over the MaxSize, a new file is created.
 func (l *Logger) openExistingOrNew(writeLen int) error {
+	l.setBucket()
 	l.mill()
 
 	filename := l.filename()
@@ -304,16 +397,21 @@ func (l *Logger) openExistingOrNew(writeLen int) error {
 		return l.openNew()
 	}
 	l.file = file
-	if l.wrapper == nil {
-		l.wrapper = defaultWriterWrapper
-	}
-	l.writer = l.wrapper(file)
+	l.writer = l.newWriter(file)
 	l.size = info.Size()
 	return nil
 }
 
 // filename generates the name of the logfile from the current time.
 func (l *Logger) filename() string {
+	if l.RotateInterval > 0 {
+		return l.bucketFilename(l.bucket)
+	}
+	return l.baseFilename()
+}
+
+// baseFilename returns the name of the logfile without the interval.
+func (l *Logger) baseFilename() string {
 	if l.Filename != "" {
 		return l.Filename
 	}
@@ -321,6 +419,36 @@ func (l *Logger) filename() string {
 	return filepath.Join(os.TempDir(), name)
 }
 
+// now returns the current time, in UTC unless LocalTime is set.
+func (l *Logger) now() time.Time {
+	t := currentTime()
+	if !l.LocalTime {
+		t = t.UTC()
+	}
+	return t
+}
+
+// setBucket sets the interval of the logfile to the current one.
+func (l *Logger) setBucket() {
+	if l.RotateInterval > 0 {
+		l.bucket = l.now().Truncate(l.RotateInterval)
+	}
+}
+
+// bucketFormat returns the time format of the interval in the file names.
+func (l *Logger) bucketFormat() string {
+	if l.RotateInterval%time.Hour == 0 {
+		return hourBucketFormat
+	}
+	return minuteBucketFormat
+}
+
+// bucketFilename returns the name of the logfile of the interval starting at bucket.
+func (l *Logger) bucketFilename(bucket time.Time) string {
+	prefix, ext := l.prefixAndExt()
+	return filepath.Join(l.dir(), prefix+bucket.Format(l.bucketFormat())+ext)
+}
+
 // millRunOnce performs compression and removal of stale log files.
 // Log files are compressed if enabled via configuration and old log
 // files are removed, keeping at most l.MaxBackups files, as long as
@@ -388,7 +516,7 @@ func (l *Logger) millRunOnce() error {
 	}
 	for _, f := range compress {
 		fn := filepath.Join(l.dir(), f.Name())
-		errCompress := compressLogFile(fn, fn+compressSuffix)
+		errCompress := compressLogFile(fn, fn+compressSuffix, l.compressionLevel())
 		if err == nil && errCompress != nil {
 			err = errCompress
 		}
@@ -429,9 +557,13 @@ func (l *Logger) oldLogFiles() ([]logInfo, error) {
 	logFiles := []logInfo{}
 
 	prefix, ext := l.prefixAndExt()
+	current := ""
+	if l.RotateInterval > 0 {
+		current = filepath.Base(l.bucketFilename(l.now().Truncate(l.RotateInterval)))
+	}
 
 	for _, f := range files {
-		if f.IsDir() {
+		if f.IsDir() || f.Name() == current {
 			continue
 		}
 		if t, err := l.timeFromName(f.Name(), prefix, ext); err == nil {
@@ -462,6 +594,16 @@ func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, error) {
 		return time.Time{}, errors.New("mismatched extension")
 	}
 	ts := filename[len(prefix) : len(filename)-len(ext)]
+	if l.RotateInterval > 0 {
+		// interval files are named prefix-bucket.ext, and rotated within the interval as prefix-bucket-timestamp.ext
+		layout := l.bucketFormat()
+		if len(ts) == len(layout) {
+			return time.Parse(layout, ts)
+		}
+		if len(ts) > len(layout) && ts[len(layout)] == '-' {
+			ts = ts[len(layout)+1:]
+		}
+	}
 	return time.Parse(backupTimeFormat, ts)
 }
 
@@ -473,23 +615,31 @@ func (l *Logger) max() int64 {
 	return int64(l.MaxSize) * int64(megabyte)
 }
 
+// compressionLevel returns the gzip level used to compress rotated log files.
+func (l *Logger) compressionLevel() int {
+	if l.CompressionLevel == 0 {
+		return gzip.DefaultCompression
+	}
+	return l.CompressionLevel
+}
+
 // dir returns the directory for the current filename.
 func (l *Logger) dir() string {
-	return filepath.Dir(l.filename())
+	return filepath.Dir(l.baseFilename())
 }
 
 // prefixAndExt returns the filename part and extension part from the Logger's
 // filename.
 func (l *Logger) prefixAndExt() (prefix, ext string) {
-	filename := filepath.Base(l.filename())
+	filename := filepath.Base(l.baseFilename())
 	ext = filepath.Ext(filename)
 	prefix = filename[:len(filename)-len(ext)] + "-"
 	return prefix, ext
 }
 
-// compressLogFile compresses the given log file, removing the
-// uncompressed log file if successful.
-func compressLogFile(src, dst string) (err error) {
+// compressLogFile compresses the given log file with the given gzip level,
+// removing the uncompressed log file if successful.
+func compressLogFile(src, dst string, level int) (err error) {
 	f, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %v", err)
@@ -513,8 +663,6 @@ func compressLogFile(src, dst string) (err error) {
 	}
 	defer gzf.Close()
 
-	gz := gzip.NewWriter(gzf)
-
 	defer func() {
 		if err != nil {
 			os.Remove(dst)
@@ -522,6 +670,10 @@ func compressLogFile(src, dst string) (err error) {
 		}
 	}()
 
+	gz, err := gzip.NewWriterLevel(gzf, level)
+	if err != nil {
+		return err
+	}
 	if _, err := io.Copy(gz, f); err != nil {
 		return err
 	}
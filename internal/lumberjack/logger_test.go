@@ -0,0 +1,187 @@
+package lumberjack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gzip header byte 8 (XFL) is 2 for the best compression level and 4 for the fastest one.
+const gzipXFLOffset = 8
+
+func writeTestLogFile(t *testing.T, name string) []byte {
+	data := []byte(strings.Repeat("2024-06-01 00:00:00|info|main.go:10|-|hello world|{\"a\":1}\n", 2000))
+	assert.Nil(t, os.WriteFile(name, data, 0644))
+	return data
+}
+
+func TestCompressLogFileLevel(t *testing.T) {
+	dir := t.TempDir()
+	sizes := map[int]int{}
+	for _, level := range []int{gzip.BestSpeed, gzip.BestCompression} {
+		src := filepath.Join(dir, "server.log")
+		data := writeTestLogFile(t, src)
+
+		assert.Nil(t, compressLogFile(src, src+compressSuffix, level))
+		_, err := os.Stat(src)
+		assert.True(t, os.IsNotExist(err))
+
+		gz, err := os.ReadFile(src + compressSuffix)
+		assert.Nil(t, err)
+		sizes[level] = len(gz)
+		if level == gzip.BestSpeed {
+			assert.Equal(t, byte(4), gz[gzipXFLOffset])
+		} else {
+			assert.Equal(t, byte(2), gz[gzipXFLOffset])
+		}
+
+		r, err := gzip.NewReader(bytes.NewReader(gz))
+		assert.Nil(t, err)
+		decompressed, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, data, decompressed)
+	}
+	assert.True(t, sizes[gzip.BestCompression] <= sizes[gzip.BestSpeed])
+}
+
+func TestCompressLogFileInvalidLevel(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "server.log")
+	writeTestLogFile(t, src)
+
+	assert.NotNil(t, compressLogFile(src, src+compressSuffix, 42))
+	_, err := os.Stat(src)
+	assert.Nil(t, err)
+	_, err = os.Stat(src + compressSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCompressionLevelDefault(t *testing.T) {
+	l := &Logger{}
+	assert.Equal(t, gzip.DefaultCompression, l.compressionLevel())
+	l.CompressionLevel = gzip.BestSpeed
+	assert.Equal(t, gzip.BestSpeed, l.compressionLevel())
+}
+
+func TestRotateCopyTruncate(t *testing.T) {
+	megabyte = 1
+	defer func() {
+		megabyte = 1024 * 1024
+	}()
+
+	filename := filepath.Join(t.TempDir(), "server.log")
+	l := &Logger{
+		Filename:     filename,
+		MaxSize:      10,
+		CopyTruncate: true,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("first\n"))
+	assert.Nil(t, err)
+	before, err := os.Stat(filename)
+	assert.Nil(t, err)
+
+	// exceeds MaxSize and triggers the rotation
+	_, err = l.Write([]byte("second\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+
+	after, err := os.Stat(filename)
+	assert.Nil(t, err)
+	assert.True(t, os.SameFile(before, after), "log file should keep its inode")
+
+	data, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "second\n", string(data))
+
+	backups, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(backups))
+	data, err = os.ReadFile(filepath.Join(filepath.Dir(filename), backups[0].Name()))
+	assert.Nil(t, err)
+	assert.Equal(t, "first\n", string(data))
+}
+
+func TestRotateInterval(t *testing.T) {
+	now := time.Date(2024, 6, 1, 14, 59, 59, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	defer func() {
+		currentTime = time.Now
+	}()
+
+	dir := t.TempDir()
+	l := &Logger{
+		Filename:       filepath.Join(dir, "server.log"),
+		RotateInterval: time.Hour,
+		FlushInterval:  time.Hour,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("at 14\n"))
+	assert.Nil(t, err)
+	now = now.Add(time.Second)
+	_, err = l.Write([]byte("at 15\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+
+	// the buffered line of 14:00 is flushed before moving to 15:00
+	data, err := os.ReadFile(filepath.Join(dir, "server-2024060114.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "at 14\n", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "server-2024060115.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "at 15\n", string(data))
+
+	// the file of the current hour is reopened, the one of the previous hour is a backup
+	_, err = l.Write([]byte("again at 15\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+	data, err = os.ReadFile(filepath.Join(dir, "server-2024060115.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "at 15\nagain at 15\n", string(data))
+
+	backups, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(backups))
+	assert.Equal(t, "server-2024060114.log", backups[0].Name())
+	assert.Equal(t, time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC), backups[0].timestamp)
+}
+
+func TestRotateIntervalWithMaxSize(t *testing.T) {
+	megabyte = 1
+	now := time.Date(2024, 6, 1, 14, 30, 0, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	defer func() {
+		megabyte = 1024 * 1024
+		currentTime = time.Now
+	}()
+
+	dir := t.TempDir()
+	l := &Logger{
+		Filename:       filepath.Join(dir, "server.log"),
+		MaxSize:        10,
+		RotateInterval: time.Hour,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("first\n"))
+	assert.Nil(t, err)
+	_, err = l.Write([]byte("second\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, l.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, "server-2024060114.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "second\n", string(data))
+	backups, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(backups))
+	assert.Equal(t, "server-2024060114-2024-06-01T14-30-00.000.log", backups[0].Name())
+}
@@ -2,6 +2,7 @@ package lumberjack
 
 import (
 	"io"
+	"time"
 
 	"github.com/caser789/logger/internal/writer"
 )
@@ -12,12 +13,24 @@ var (
 	defaultWriterWrapper WriterWrapper
 )
 
+const defaultBufferSize = 4 * 1024
+
 func init() {
-	WithDoubleBufWrapper(4 * 1024)
+	WithDoubleBufWrapper(defaultBufferSize)
 }
 
 func WithDoubleBufWrapper(size int) {
-	defaultWriterWrapper = func(w io.Writer) writer.BufferedWriter {
-		return writer.NewDoubleBufWriterSize(w, size)
+	WithDoubleBufWrapperPeriod(size, 0)
+}
+
+// WithDoubleBufWrapperPeriod sets the default wrapper to a double buffer writer of the given size,
+// flushed every period. The writer's default period is used if period is not positive.
+func WithDoubleBufWrapperPeriod(size int, period time.Duration) {
+	defaultWriterWrapper = doubleBufWrapper(size, period)
+}
+
+func doubleBufWrapper(size int, period time.Duration) WriterWrapper {
+	return func(w io.Writer) writer.BufferedWriter {
+		return writer.NewDoubleBufWriterSizePeriod(w, size, period)
 	}
 }
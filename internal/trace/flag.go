@@ -78,6 +78,22 @@ func IsSpanContextSampled(sc SpanContext) bool {
 	return (getSpecialFlag(sc)&traceFlagSampled) == traceFlagSampled || IsSpanContextDebug(sc)
 }
 
+// GetSampleReason returns why the request is sampled or not, as one of the SampleReason constants.
+func GetSampleReason(sc SpanContext) string {
+	switch {
+	case sc == nil:
+		return SampleReasonNoTrace
+	case IsSpanContextDebug(sc):
+		return SampleReasonDebug
+	case IsSpanContextSampled(sc):
+		return SampleReasonSampled
+	case IsSpanContextCritical(sc):
+		return SampleReasonCritical
+	default:
+		return SampleReasonNotSampled
+	}
+}
+
 // IsSpanContextCritical indicates whether the request is critical.
 func IsSpanContextCritical(sc SpanContext) bool {
 	if sc == nil {
@@ -87,6 +103,21 @@ func IsSpanContextCritical(sc SpanContext) bool {
 	return (getSpecialFlag(sc) & traceFlagCritical) == traceFlagCritical
 }
 
+// IsSpanContextInternal indicates whether the request originates from an internal service.
+// It is false for the old format and the unknown type markers, e.g. of the ids generated without this lib,
+// whose flag bit is random.
+func IsSpanContextInternal(sc SpanContext) bool {
+	if sc == nil {
+		return false
+	}
+	switch GetRequestType(sc) {
+	case ReqTypeOldFormat, ReqTypeUnknown:
+		return false
+	}
+
+	return (getSpecialFlag(sc) & traceFlagInternal) == traceFlagInternal
+}
+
 func getSpecialFlag(sc SpanContext) byte {
 	return sc.TraceID()[traceIDSize-1]
 }
@@ -0,0 +1,93 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSpanContextInternal(t *testing.T) {
+	scg := NewSpanContextGenerator("test")
+	sampled := true
+
+	sc := scg.NewSpanContext(IsInternal(true), IsCritical(true), IsSampled(&sampled), IsShadow(true))
+	assert.True(t, IsSpanContextInternal(sc))
+	// doesn't collide with the other flags
+	assert.True(t, IsSpanContextCritical(sc))
+	assert.True(t, IsSpanContextSampled(sc))
+	assert.True(t, IsSpanContextShadow(sc))
+	assert.False(t, IsSpanContextDebug(sc))
+	assert.Equal(t, ReqTypeShadow, GetRequestType(sc))
+
+	// survives child spans
+	child := sc.NewChildSpanContext().NewChildSpanContext()
+	assert.True(t, IsSpanContextInternal(child))
+
+	// round-trips through string and bytes
+	fromString, err := NewSpanContextFromString(sc.String())
+	assert.Nil(t, err)
+	assert.True(t, IsSpanContextInternal(fromString))
+	fromBytes, err := NewSpanContextFromBytes(sc.Bytes())
+	assert.Nil(t, err)
+	assert.True(t, IsSpanContextInternal(fromBytes))
+
+	external := scg.NewSpanContext(IsCritical(true), IsSampled(&sampled))
+	assert.False(t, IsSpanContextInternal(external))
+	assert.False(t, IsSpanContextInternal(nil))
+
+	// a normal request gets the type marker of the new format
+	internal := scg.NewSpanContext(IsInternal(true))
+	assert.True(t, IsSpanContextInternal(internal))
+	assert.Equal(t, ReqTypeNormal, GetRequestType(internal))
+	debug := scg.NewSpanContext(IsInternal(true), IsDebug(true))
+	assert.True(t, IsSpanContextInternal(debug))
+	assert.True(t, IsSpanContextDebug(debug))
+	assert.Equal(t, ReqTypeDebug, GetRequestType(debug))
+
+	// the bit of the old format and of the unknown type markers isn't trusted
+	for _, flag := range []byte{traceFlagInternal, traceFlagInternal | traceFlagOldDebug, typeMarkerMask | traceFlagInternal} {
+		id := append([]byte(nil), internal.Bytes()...)
+		id[traceIDSize-1] = flag
+		foreign, err := NewSpanContextFromBytes(id)
+		assert.Nil(t, err)
+		assert.False(t, IsSpanContextInternal(foreign), flag)
+	}
+}
+
+func TestChildSpanContextKeepsFlags(t *testing.T) {
+	scg := NewSpanContextGenerator("test")
+	sampled, notSampled := true, false
+
+	critical := scg.NewSpanContext(IsCritical(true), IsSampled(&sampled))
+	stress := scg.NewSpanContext(IsFromStressTest(true), IsSampled(&notSampled))
+	debug := scg.NewSpanContext(IsDebug(true))
+	for _, root := range []SpanContext{critical, stress, debug} {
+		child := root.NewChildSpanContext()
+		grandChild := child.NewChildSpanContext()
+		for _, sc := range []SpanContext{child, grandChild} {
+			assert.Equal(t, root.TraceID(), sc.TraceID())
+			assert.Equal(t, IsSpanContextCritical(root), IsSpanContextCritical(sc))
+			assert.Equal(t, IsSpanContextSampled(root), IsSpanContextSampled(sc))
+			assert.Equal(t, IsSpanContextDebug(root), IsSpanContextDebug(sc))
+			assert.Equal(t, GetTypeMarker(root), GetTypeMarker(sc))
+			assert.Equal(t, GetRequestType(root), GetRequestType(sc))
+		}
+	}
+
+	assert.True(t, IsSpanContextCritical(critical.NewChildSpanContext()))
+	assert.True(t, IsSpanContextSampled(critical.NewChildSpanContext()))
+	assert.Equal(t, ReqTypeStressTest, GetRequestType(stress.NewChildSpanContext()))
+	assert.True(t, IsSpanContextFromStressTest(stress.NewChildSpanContext()))
+	assert.False(t, IsSpanContextSampled(stress.NewChildSpanContext()))
+}
+
+func TestGetSampleReason(t *testing.T) {
+	scg := NewSpanContextGenerator("test")
+	sampled, notSampled := true, false
+
+	assert.Equal(t, SampleReasonNoTrace, GetSampleReason(nil))
+	assert.Equal(t, SampleReasonDebug, GetSampleReason(scg.NewSpanContext(IsDebug(true))))
+	assert.Equal(t, SampleReasonSampled, GetSampleReason(scg.NewSpanContext(IsSampled(&sampled), IsCritical(true))))
+	assert.Equal(t, SampleReasonCritical, GetSampleReason(scg.NewSpanContext(IsSampled(&notSampled), IsCritical(true))))
+	assert.Equal(t, SampleReasonNotSampled, GetSampleReason(scg.NewSpanContext(IsSampled(&notSampled))))
+}
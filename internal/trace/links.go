@@ -0,0 +1,13 @@
+package trace
+
+// Links returns the string forms of primary followed by others, e.g. to log the upstream requests a batch job
+// follows from. Nil span contexts are skipped.
+func Links(primary SpanContext, others ...SpanContext) []string {
+	links := make([]string, 0, len(others)+1)
+	for _, sc := range append([]SpanContext{primary}, others...) {
+		if sc != nil {
+			links = append(links, sc.String())
+		}
+	}
+	return links
+}
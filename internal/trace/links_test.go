@@ -0,0 +1,16 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinks(t *testing.T) {
+	scg := NewSpanContextGenerator("test")
+	primary, first, second := scg.NewSpanContext(), scg.NewSpanContext(), scg.NewSpanContext()
+
+	assert.Equal(t, []string{primary.String(), first.String(), second.String()}, Links(primary, first, nil, second))
+	assert.Equal(t, []string{first.String()}, Links(nil, first))
+	assert.Equal(t, []string{}, Links(nil))
+}
@@ -0,0 +1,281 @@
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// LogRecord is a log entry of a Span, see Span.LogFields.
+type LogRecord struct {
+	Time   time.Time
+	Fields map[string]interface{}
+}
+
+// Reporter sends finished spans to a tracing platform.
+type Reporter interface {
+	// Report is called once for each finished and sampled span, started at start and finished at finish,
+	// which is never before start.
+	Report(spanContext SpanContext, name string, tags map[string]interface{}, logs []LogRecord, start, finish time.Time)
+}
+
+// ReportedSpan is a span reported to an InMemoryReporter.
+type ReportedSpan struct {
+	SpanContext SpanContext
+	Name        string
+	Tags        map[string]interface{}
+	Logs        []LogRecord
+	Start       time.Time
+	Finish      time.Time
+	Duration    time.Duration
+}
+
+// InMemoryReporter keeps the reported spans in memory, e.g. for tests.
+type InMemoryReporter struct {
+	mutex sync.Mutex
+	spans []ReportedSpan
+}
+
+// NewInMemoryReporter returns an empty InMemoryReporter.
+func NewInMemoryReporter() *InMemoryReporter {
+	return &InMemoryReporter{}
+}
+
+// Report implements Reporter.
+func (r *InMemoryReporter) Report(spanContext SpanContext, name string, tags map[string]interface{}, logs []LogRecord,
+	start, finish time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = append(r.spans, ReportedSpan{
+		SpanContext: spanContext,
+		Name:        name,
+		Tags:        tags,
+		Logs:        logs,
+		Start:       start,
+		Finish:      finish,
+		Duration:    finish.Sub(start),
+	})
+}
+
+// Spans returns a copy of the reported spans, in report order.
+func (r *InMemoryReporter) Spans() []ReportedSpan {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]ReportedSpan(nil), r.spans...)
+}
+
+// Reset drops the reported spans.
+func (r *InMemoryReporter) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = nil
+}
+
+// reportingTracer is a Tracer whose spans are sent to a Reporter when finished.
+type reportingTracer struct {
+	reporter  Reporter
+	generator SpanContextGenerator
+	// timeNow exists so it can be mocked out by tests.
+	timeNow func() time.Time
+}
+
+// NewReportingTracer returns a Tracer whose spans accumulate their tags and logs, and are sent to reporter
+// when finished, if they are sampled. generator creates the span context of the spans created without one.
+func NewReportingTracer(reporter Reporter, generator SpanContextGenerator) Tracer {
+	return &reportingTracer{
+		reporter:  reporter,
+		generator: generator,
+		timeNow:   time.Now,
+	}
+}
+
+// NewSpan returns a root Span whose span context is initiated with the given span context.
+func (rt *reportingTracer) NewSpan(name string, spanContext SpanContext) (Span, error) {
+	return rt.NewSpanWithOptions(name, spanContext)
+}
+
+// NewSpanWithOptions returns a root Span with options.
+func (rt *reportingTracer) NewSpanWithOptions(name string, spanContext SpanContext, options ...NewSpanOption) (Span, error) {
+	opts := NewSpanOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+	if opts.StartTime.IsZero() {
+		opts.StartTime = rt.timeNow()
+	}
+	if spanContext == nil {
+		spanContext = rt.generator.NewSpanContext()
+	}
+	return &reportingSpan{
+		tracer: rt,
+		ctx:    spanContext,
+		name:   name,
+		start:  opts.StartTime,
+		tags:   map[string]interface{}{},
+	}, nil
+}
+
+// reportingSpan is a Span of a reportingTracer, its tags and logs are ignored once finished.
+type reportingSpan struct {
+	tracer *reportingTracer
+	ctx    SpanContext
+	name   string
+	start  time.Time
+
+	mutex    sync.Mutex
+	tags     map[string]interface{}
+	logs     []LogRecord
+	finished bool
+}
+
+// Context returns SpanContext of current Span.
+func (rs *reportingSpan) Context() SpanContext {
+	return rs.ctx
+}
+
+// NewChildSpan creates and returns a child Span of current Span.
+func (rs *reportingSpan) NewChildSpan(name string) (Span, error) {
+	return rs.tracer.NewSpan(name, rs.ctx.NewChildSpanContext())
+}
+
+// SetTag adds a tag to the span.
+func (rs *reportingSpan) SetTag(key string, value interface{}) Span {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	if !rs.finished {
+		rs.tags[key] = value
+	}
+	return rs
+}
+
+// SetTags set tags to current Span, pairs whose key is not a string are ignored and reported, see SetErrorHandler.
+func (rs *reportingSpan) SetTags(keyValues ...interface{}) Span {
+	tags, err := pairs(keyValues)
+	if err != nil {
+		handleError(fmt.Errorf("trace: SetTags of span %q: %w", rs.name, err))
+	}
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	if rs.finished {
+		return rs
+	}
+	for k, v := range tags {
+		rs.tags[k] = v
+	}
+	return rs
+}
+
+// SetDebugTags is SetTags applied only when the span context is on debug mode.
+func (rs *reportingSpan) SetDebugTags(keyValues ...interface{}) Span {
+	if !IsSpanContextDebug(rs.ctx) {
+		return rs
+	}
+	return rs.SetTags(keyValues...)
+}
+
+// LogFields adds a log entry to current Span, pairs whose key is not a string are ignored and reported,
+// see SetErrorHandler.
+func (rs *reportingSpan) LogFields(keyValues ...interface{}) Span {
+	fields, err := pairs(keyValues)
+	if err != nil {
+		handleError(fmt.Errorf("trace: LogFields of span %q: %w", rs.name, err))
+	}
+	record := LogRecord{Time: rs.tracer.timeNow(), Fields: fields}
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	if !rs.finished {
+		rs.logs = append(rs.logs, record)
+	}
+	return rs
+}
+
+// LogDebugFields is LogFields applied only when the span context is on debug mode.
+func (rs *reportingSpan) LogDebugFields(keyValues ...interface{}) Span {
+	if !IsSpanContextDebug(rs.ctx) {
+		return rs
+	}
+	return rs.LogFields(keyValues...)
+}
+
+// Finish ends the span and reports it if it is sampled.
+func (rs *reportingSpan) Finish() {
+	rs.FinishWithOptions()
+}
+
+// FinishWithOptions ends the span with options and reports it if it is sampled.
+// Only the first call has effect.
+func (rs *reportingSpan) FinishWithOptions(options ...FinishSpanOption) {
+	opts := FinishSpanOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+	if opts.FinishTime.IsZero() {
+		opts.FinishTime = rs.tracer.timeNow()
+	}
+
+	rs.mutex.Lock()
+	if rs.finished {
+		rs.mutex.Unlock()
+		return
+	}
+	rs.finished = true
+	tags, logs := rs.tags, rs.logs
+	rs.mutex.Unlock()
+
+	if IsSpanContextSampled(rs.ctx) {
+		rs.tracer.reporter.Report(rs.ctx, rs.name, tags, logs, rs.start, spanFinish(rs.start, opts.FinishTime))
+	}
+}
+
+// spanFinish returns finish, or start if finish is before it, e.g. by clock skew between the explicit StartTime
+// and FinishTime.
+func spanFinish(start, finish time.Time) time.Time {
+	if finish.Before(start) {
+		return start
+	}
+	return finish
+}
+
+// pairs returns the key-value pairs of keyValues, skipping the pairs whose key is not a string and a trailing key,
+// and an error describing the skipped arguments, if any.
+func pairs(keyValues []interface{}) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(keyValues)/2)
+	var problems []string
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if k, ok := keyValues[i].(string); ok {
+			m[k] = keyValues[i+1]
+		} else {
+			problems = append(problems, fmt.Sprintf("key at index %d is a %T, not a string", i, keyValues[i]))
+		}
+	}
+	if len(keyValues)%2 != 0 {
+		problems = append(problems, fmt.Sprintf("odd number of arguments %d, key %v has no value",
+			len(keyValues), keyValues[len(keyValues)-1]))
+	}
+	if len(problems) > 0 {
+		return m, errors.New(strings.Join(problems, ", "))
+	}
+	return m, nil
+}
+
+var errorHandler atomic.Value
+
+type registeredErrorHandler struct {
+	handler func(error)
+}
+
+// SetErrorHandler sets the function called with the errors of the malformed calls to the spans of the reporting
+// tracers, e.g. SetTags with an odd number of arguments or a key that is not a string. They are dropped by default.
+func SetErrorHandler(handler func(error)) {
+	errorHandler.Store(registeredErrorHandler{handler: handler})
+}
+
+func handleError(err error) {
+	if h, ok := errorHandler.Load().(registeredErrorHandler); ok && h.handler != nil {
+		h.handler(err)
+	}
+}
@@ -0,0 +1,105 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestReportingTracer() (*reportingTracer, *InMemoryReporter) {
+	reporter := NewInMemoryReporter()
+	tracer := NewReportingTracer(reporter, NewSpanContextGenerator("test")).(*reportingTracer)
+	return tracer, reporter
+}
+
+func TestReportingTracer(t *testing.T) {
+	tracer, reporter := newTestReportingTracer()
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tracer.timeNow = func() time.Time { return start }
+
+	sampled := true
+	sc := NewSpanContextGenerator("test").NewSpanContext(IsSampled(&sampled))
+	span, err := tracer.NewSpan("query", sc)
+	assert.Nil(t, err)
+	span.SetTag("db", "users").SetTags("rows", 3, 42, "ignored").SetDebugTags("debug", true)
+	span.LogFields("event", "cache miss")
+	span.FinishWithOptions(FinishTime(start.Add(time.Second)))
+	span.Finish()
+
+	spans := reporter.Spans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, sc, spans[0].SpanContext)
+	assert.Equal(t, "query", spans[0].Name)
+	assert.Equal(t, map[string]interface{}{"db": "users", "rows": 3}, spans[0].Tags)
+	assert.Equal(t, []LogRecord{{Time: start, Fields: map[string]interface{}{"event": "cache miss"}}}, spans[0].Logs)
+	assert.Equal(t, time.Second, spans[0].Duration)
+}
+
+func TestReportingSpanDuration(t *testing.T) {
+	tracer, reporter := newTestReportingTracer()
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	sampled := true
+	sc := NewSpanContextGenerator("test").NewSpanContext(IsSampled(&sampled))
+	span, _ := tracer.NewSpanWithOptions("explicit", sc, StartTime(start))
+	span.FinishWithOptions(FinishTime(start.Add(1500 * time.Millisecond)))
+	// a finish time before the start time, e.g. from another clock
+	skewed, _ := tracer.NewSpanWithOptions("skewed", sc, StartTime(start))
+	skewed.FinishWithOptions(FinishTime(start.Add(-time.Second)))
+
+	spans := reporter.Spans()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, 1500*time.Millisecond, spans[0].Duration)
+	assert.Equal(t, start, spans[0].Start)
+	assert.Equal(t, start.Add(1500*time.Millisecond), spans[0].Finish)
+	assert.Equal(t, time.Duration(0), spans[1].Duration)
+	assert.Equal(t, start, spans[1].Finish)
+}
+
+func TestReportingTracerChildAndSampling(t *testing.T) {
+	tracer, reporter := newTestReportingTracer()
+
+	sampled, notSampled := true, false
+	root, _ := tracer.NewSpan("root",
+		NewSpanContextGenerator("test").NewSpanContext(IsSampled(&sampled), IsFromStressTest(true)))
+	child, err := root.NewChildSpan("child")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, SpanDepth(child.Context()))
+	child.Finish()
+	root.Finish()
+
+	dropped, _ := tracer.NewSpan("dropped", NewSpanContextGenerator("test").NewSpanContext(IsSampled(&notSampled)))
+	dropped.Finish()
+
+	spans := reporter.Spans()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, "root", spans[1].Name)
+}
+
+func TestReportingSpanMalformedArgs(t *testing.T) {
+	var errs []string
+	SetErrorHandler(func(err error) { errs = append(errs, err.Error()) })
+	defer SetErrorHandler(nil)
+
+	tracer, reporter := newTestReportingTracer()
+	sampled := true
+	span, _ := tracer.NewSpan("query", NewSpanContextGenerator("test").NewSpanContext(IsSampled(&sampled)))
+	span.SetTags("db", "users", "rows")
+	span.SetTags(42, "answer", "ok", true)
+	span.LogFields("event")
+	span.SetTags("valid", 1)
+	span.Finish()
+
+	assert.Equal(t, []string{
+		`trace: SetTags of span "query": odd number of arguments 3, key rows has no value`,
+		`trace: SetTags of span "query": key at index 0 is a int, not a string`,
+		`trace: LogFields of span "query": odd number of arguments 1, key event has no value`,
+	}, errs)
+	spans := reporter.Spans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, map[string]interface{}{"db": "users", "ok": true, "valid": 1}, spans[0].Tags)
+	assert.Equal(t, 1, len(spans[0].Logs))
+	assert.Empty(t, spans[0].Logs[0].Fields)
+}
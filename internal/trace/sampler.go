@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sync"
+	"time"
 )
 
 const defaultSamplingProbability = 0.001
@@ -48,3 +50,94 @@ func NewProbabilisticSampler(samplingRate float64) *ProbabilisticSampler {
 		samplingRate: math.Max(0.0, math.Min(samplingRate, 1.0)),
 	}
 }
+
+// RateLimitingSampler is a sampler that samples at most maxTracesPerSecond traces per second.
+// It uses a token bucket which is refilled lazily on every IsSampled call, so no background go-routine is needed.
+type RateLimitingSampler struct {
+	maxTracesPerSecond float64
+
+	mutex      sync.Mutex
+	balance    float64
+	maxBalance float64
+	lastTick   time.Time
+	timeNow    func() time.Time
+}
+
+// IsSampled implements IsSampled() of Sampler.
+func (s *RateLimitingSampler) IsSampled(_ context.Context) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.timeNow()
+	elapsed := now.Sub(s.lastTick).Seconds()
+	s.lastTick = now
+	s.balance = math.Min(s.balance+elapsed*s.maxTracesPerSecond, s.maxBalance)
+	if s.balance < 1.0 {
+		return false
+	}
+	s.balance -= 1.0
+	return true
+}
+
+// Close implements Close() of Sampler.
+func (s *RateLimitingSampler) Close() {}
+
+// MaxTracesPerSecond returns the maximum number of traces sampled per second.
+func (s *RateLimitingSampler) MaxTracesPerSecond() float64 {
+	return s.maxTracesPerSecond
+}
+
+// String is used to log sampler details.
+func (s *RateLimitingSampler) String() string {
+	return fmt.Sprintf("RateLimitingSampler(maxTracesPerSecond=%v)", s.maxTracesPerSecond)
+}
+
+// NewRateLimitingSampler creates a RateLimitingSampler.
+// The bucket starts full and can hold up to one second worth of traces (at least one trace).
+func NewRateLimitingSampler(maxTracesPerSecond float64) *RateLimitingSampler {
+	maxTracesPerSecond = math.Max(0.0, maxTracesPerSecond)
+	maxBalance := math.Max(maxTracesPerSecond, 1.0)
+	return &RateLimitingSampler{
+		maxTracesPerSecond: maxTracesPerSecond,
+		balance:            maxBalance,
+		maxBalance:         maxBalance,
+		lastTick:           time.Now(),
+		timeNow:            time.Now,
+	}
+}
+
+// GuaranteedThroughputSampler is a sampler that combines a ProbabilisticSampler and a RateLimitingSampler.
+// A trace is sampled only if the ProbabilisticSampler decides to sample it, and the RateLimitingSampler
+// still has budget left. That is, the probabilistic decision comes first and the rate limit caps the
+// total number of sampled traces per second. The rate limiter is only consulted for positive decisions,
+// so unsampled traces don't consume its budget.
+type GuaranteedThroughputSampler struct {
+	probabilisticSampler *ProbabilisticSampler
+	rateLimitingSampler  *RateLimitingSampler
+}
+
+// IsSampled implements IsSampled() of Sampler.
+func (s *GuaranteedThroughputSampler) IsSampled(ctx context.Context) bool {
+	return s.probabilisticSampler.IsSampled(ctx) && s.rateLimitingSampler.IsSampled(ctx)
+}
+
+// Close implements Close() of Sampler.
+func (s *GuaranteedThroughputSampler) Close() {
+	s.probabilisticSampler.Close()
+	s.rateLimitingSampler.Close()
+}
+
+// String is used to log sampler details.
+func (s *GuaranteedThroughputSampler) String() string {
+	return fmt.Sprintf("GuaranteedThroughputSampler(samplingRate=%v, maxTracesPerSecond=%v)",
+		s.probabilisticSampler.SamplingRate(), s.rateLimitingSampler.MaxTracesPerSecond())
+}
+
+// NewGuaranteedThroughputSampler creates a GuaranteedThroughputSampler which samples traces with probability
+// samplingRate, but never more than maxTracesPerSecond traces per second.
+func NewGuaranteedThroughputSampler(samplingRate float64, maxTracesPerSecond float64) *GuaranteedThroughputSampler {
+	return &GuaranteedThroughputSampler{
+		probabilisticSampler: NewProbabilisticSampler(samplingRate),
+		rateLimitingSampler:  NewRateLimitingSampler(maxTracesPerSecond),
+	}
+}
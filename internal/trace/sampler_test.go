@@ -0,0 +1,88 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+func TestRateLimitingSamplerConcurrent(t *testing.T) {
+	const maxTracesPerSecond = 100
+	sampler := NewRateLimitingSampler(maxTracesPerSecond)
+	defer sampler.Close()
+
+	var sampled atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(200 * time.Millisecond)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if sampler.IsSampled(context.Background()) {
+					sampled.Inc()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+
+	// the initial burst is bounded by the bucket size, then it refills at maxTracesPerSecond
+	limit := int64(maxTracesPerSecond + elapsed*maxTracesPerSecond + 1)
+	assert.True(t, sampled.Load() > 0)
+	assert.True(t, sampled.Load() <= limit, "sampled %d, limit %d", sampled.Load(), limit)
+}
+
+func TestRateLimitingSamplerRefill(t *testing.T) {
+	now := time.Now()
+	sampler := NewRateLimitingSampler(2)
+	sampler.lastTick = now
+	sampler.timeNow = func() time.Time { return now }
+
+	assert.True(t, sampler.IsSampled(context.Background()))
+	assert.True(t, sampler.IsSampled(context.Background()))
+	assert.False(t, sampler.IsSampled(context.Background()))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, sampler.IsSampled(context.Background()))
+	assert.False(t, sampler.IsSampled(context.Background()))
+}
+
+func TestGuaranteedThroughputSamplerCeiling(t *testing.T) {
+	sampler := NewGuaranteedThroughputSampler(1.0, 5)
+	defer sampler.Close()
+
+	sampled := 0
+	for i := 0; i < 100; i++ {
+		if sampler.IsSampled(context.Background()) {
+			sampled++
+		}
+	}
+	// every trace passes the probabilistic sampler, the rate limiter caps them
+	assert.Equal(t, 5, sampled)
+}
+
+func TestGuaranteedThroughputSamplerFloor(t *testing.T) {
+	sampler := NewGuaranteedThroughputSampler(0.0, 100)
+	defer sampler.Close()
+
+	for i := 0; i < 100; i++ {
+		// budget is left, but the probabilistic sampler never samples
+		assert.False(t, sampler.IsSampled(context.Background()))
+	}
+	// negative decisions don't consume the rate limiter budget
+	assert.Equal(t, 100.0, sampler.rateLimitingSampler.balance)
+}
+
+func TestGuaranteedThroughputSamplerWithGenerator(t *testing.T) {
+	scg := NewSpanContextGenerator("", WithSampler(NewGuaranteedThroughputSampler(1.0, 1)))
+
+	assert.True(t, IsSpanContextSampled(scg.NewSpanContext()))
+	assert.False(t, IsSpanContextSampled(scg.NewSpanContext()))
+}
@@ -1,9 +1,11 @@
 package trace
 
 import (
+	"bytes"
+	"crypto/rand"
 	"encoding/hex"
 	"errors"
-	"math/rand"
+	"io"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +14,8 @@ import (
 const (
 	traceIDSize = 16
 	spanIDSize  = 8
+	// entropyOffset is the offset of the random bytes in the trace id, which end before the flag byte
+	entropyOffset = 10
 	// totalIDSize total byte length of traceID, spanID and parentID
 	totalIDSize = traceIDSize + spanIDSize*2
 
@@ -23,8 +27,10 @@ const (
 	traceFlagOldDebug      = 1
 	traceFlagSampled       = 1 << 1
 	traceFlagCritical      = 1 << 2
+	traceFlagInternal      = 1 << 3
 	typeMarkerForOldFormat = 0 << 5
 
+	typeMarkerForNormal     = 1 << 5
 	typeMarkerForDebug      = 2 << 5
 	typeMarkerForStressTest = 3 << 5
 	typeMarkerForShadow     = 4 << 5
@@ -45,10 +51,25 @@ const (
 	ReqTypeShadow = "shadow"
 	// ReqTypeUnknown indicates requests without a known type marker
 	ReqTypeUnknown = "unknown"
+
+	// Reasons of the sampling decision of a span context, see GetSampleReason
+
+	// SampleReasonNoTrace indicates there is no span context
+	SampleReasonNoTrace = "no_trace"
+	// SampleReasonDebug indicates requests sampled because of their debug flag
+	SampleReasonDebug = "debug"
+	// SampleReasonSampled indicates requests with sampling flag set, by the sampler or the caller
+	SampleReasonSampled = "sampled"
+	// SampleReasonCritical indicates unsampled requests with critical flag set
+	SampleReasonCritical = "critical"
+	// SampleReasonNotSampled indicates unsampled requests
+	SampleReasonNotSampled = "not_sampled"
 )
 
 var (
 	errConvertToSpanContext = errors.New("cannot convert to SpanContext")
+
+	zeroSpanID [spanIDSize]byte
 )
 
 // SpanContext represent context info of a Span, it is used to connect different Spans together into one specific trace.
@@ -89,6 +110,7 @@ type spanContext struct {
 	mutex           sync.Mutex
 	childSequenceID uint16 // next SequenceID of children
 	id              [totalIDSize]byte
+	rng             io.Reader // source of the random bytes of the children, rand.Reader if nil
 }
 
 // TraceID get byte slice of traceID
@@ -179,7 +201,39 @@ func (sc *spanContext) level() uint8 {
 	return sc.id[traceIDSize]
 }
 
-// NewChildSpanContext generate a child SpanContext based on current one
+// SpanDepth returns the nesting depth encoded in the span id: 0 for a root span, 1 for its children and so on.
+// It returns -1 for a nil span context and for the old format, whose span id may not encode the depth.
+func SpanDepth(sc SpanContext) int {
+	if sc == nil || isOldFormat(sc) {
+		return -1
+	}
+	return int(sc.SpanID()[0])
+}
+
+// IsCrossService returns whether sc has a parent, i.e. its parent id is non-zero and distinct from its span id,
+// which indicates it was created from an inbound span context rather than being the root of the trace.
+func IsCrossService(sc SpanContext) bool {
+	if sc == nil {
+		return false
+	}
+	parentID := sc.ParentID()
+	return !bytes.Equal(parentID, zeroSpanID[:]) && !bytes.Equal(parentID, sc.SpanID())
+}
+
+// Entropy returns a copy of the 5 random bytes of the trace id, e.g. as a key to deduplicate retries.
+// It returns nil for a nil span context and for the old format, whose trace id may not embed them.
+func Entropy(sc SpanContext) []byte {
+	if sc == nil || isOldFormat(sc) {
+		return nil
+	}
+	entropy := make([]byte, traceIDSize-1-entropyOffset)
+	copy(entropy, sc.TraceID()[entropyOffset:traceIDSize-1])
+	return entropy
+}
+
+// NewChildSpanContext generate a child SpanContext based on current one.
+// The trace id is copied with the flag byte ending it, so the child keeps the
+// sampled, critical, internal and type marker bits of its parent.
 func (sc *spanContext) NewChildSpanContext() SpanContext {
 	sc.mutex.Lock()
 	currentSequenceID := sc.childSequenceID
@@ -188,15 +242,59 @@ func (sc *spanContext) NewChildSpanContext() SpanContext {
 
 	var childID [totalIDSize]byte
 	copy(childID[:], sc.TraceID())
-	newSpanID(childID[traceIDSize:traceIDSize+spanIDSize], sc.level()+1, currentSequenceID)
+	newSpanID(sc.randSource(), childID[traceIDSize:traceIDSize+spanIDSize], sc.level()+1, currentSequenceID)
 	copy(childID[traceIDSize+spanIDSize:], sc.SpanID())
 
 	childSC := &spanContext{
-		id: childID,
+		id:  childID,
+		rng: sc.rng,
 	}
 	return childSC
 }
 
+// forkN reserves n contiguous sequence ids at once and returns the n children using them.
+func (sc *spanContext) forkN(n int) []SpanContext {
+	sc.mutex.Lock()
+	firstSequenceID := sc.childSequenceID
+	sc.childSequenceID += uint16(n)
+	sc.mutex.Unlock()
+
+	children := make([]SpanContext, n)
+	for i := range children {
+		var childID [totalIDSize]byte
+		copy(childID[:], sc.TraceID())
+		newSpanID(sc.randSource(), childID[traceIDSize:traceIDSize+spanIDSize], sc.level()+1, firstSequenceID+uint16(i))
+		copy(childID[traceIDSize+spanIDSize:], sc.SpanID())
+		children[i] = &spanContext{id: childID, rng: sc.rng}
+	}
+	return children
+}
+
+// randSource returns the source of the random bytes of the children of sc, the one of the generator of its root.
+func (sc *spanContext) randSource() io.Reader {
+	if sc.rng == nil {
+		return rand.Reader
+	}
+	return sc.rng
+}
+
+// ForkN returns n child span contexts of sc with contiguous sequence ids, e.g. one for each goroutine of a fan-out.
+// The sequence ids are reserved at once, so concurrent calls on the same sc never interleave.
+// Span contexts not created by this package fall back to calling NewChildSpanContext n times.
+func ForkN(sc SpanContext, n int) []SpanContext {
+	if sc == nil || n <= 0 {
+		return nil
+	}
+	if s, ok := sc.(*spanContext); ok {
+		return s.forkN(n)
+	}
+	children := make([]SpanContext, n)
+	for i := range children {
+		children[i] = sc.NewChildSpanContext()
+	}
+	return children
+}
+
 // GetTypeMarker return the type marker for the request
 func (sc *spanContext) GetTypeMarker() int {
 	return int((sc.id[traceIDSize-1] & typeMarkerMask) >> flagBitsNonTypeMarker)
@@ -252,31 +350,29 @@ func SpanContextFromBytesToString(bytes []byte) string {
 	return spanContext.String()
 }
 
-func getRandomBytes(bs []byte) {
+// getRandomBytes fills bs with random bytes read from rng, never all zeros.
+func getRandomBytes(rng io.Reader, bs []byte) {
 	if len(bs) == 0 {
 		return
 	}
 
-	bsLen, _ := rand.Read(bs)
+	_, _ = io.ReadFull(rng, bs)
 	// avoid confuse with ancestor's parent span id
-	if bs[bsLen-1] == 0 {
-		isAllZero := true
-		for i := 0; i < bsLen-1 && bs[i] != 0; i++ {
-			isAllZero = false
-		}
-		if isAllZero {
-			bs[bsLen-1] = 1
+	for _, b := range bs {
+		if b != 0 {
+			return
 		}
 	}
+	bs[len(bs)-1] = 1
 }
 
 // newSpanID create a SpanID
-func newSpanID(spanID []byte, level uint8, sequenceID uint16) {
+func newSpanID(rng io.Reader, spanID []byte, level uint8, sequenceID uint16) {
 	spanID[0] = level
 	if sequenceID > 0 {
 		spanID[1], spanID[2] = byte(sequenceID>>8), byte(sequenceID)
 	}
-	getRandomBytes(spanID[3:spanIDSize])
+	getRandomBytes(rng, spanID[3:spanIDSize])
 }
 
 // Span represents an execution sequence in your code logic, from where it is started to the place its Finish been called.
@@ -3,29 +3,43 @@ package trace
 import (
 	"context"
 	"crypto/md5"
-	"math/rand"
-	"os"
+	"crypto/rand"
+	"io"
 	"time"
+
+	"github.com/caser789/logger/internal/utils/env"
 )
 
 // SpanContextGenerator a generator to produce new SpanContext
 type SpanContextGenerator interface {
 	NewSpanContext(options ...SpanContextOption) SpanContext
+	// NewSpanContextCtx is like NewSpanContext, but passes ctx to the sampler so it can decide by request attributes.
+	NewSpanContextCtx(ctx context.Context, options ...SpanContextOption) SpanContext
 }
 
 type cachedSpanContextGenerator struct {
 	serviceInstanceID string
 	instanceIDHash    [4]byte
 	sampler           Sampler
+	// rng is the source of the random bytes of the ids.
+	rng io.Reader
+	// timeNow exists so it can be mocked out by tests.
+	timeNow func() time.Time
 }
 
 // NewSpanContext produce SpanContext with options
 func (scg *cachedSpanContextGenerator) NewSpanContext(options ...SpanContextOption) SpanContext {
+	return scg.NewSpanContextCtx(context.Background(), options...)
+}
+
+// NewSpanContextCtx produce SpanContext with options, the sampler decides with the given ctx
+func (scg *cachedSpanContextGenerator) NewSpanContextCtx(ctx context.Context, options ...SpanContextOption) SpanContext {
 	sco := SpanContextOptions{
 		IsDebug:          false,
 		IsFromStressTest: false,
 		IsShadow:         false,
 		IsCritical:       false,
+		IsInternal:       false,
 	}
 	for _, f := range options {
 		f(&sco)
@@ -33,10 +47,11 @@ func (scg *cachedSpanContextGenerator) NewSpanContext(options ...SpanContextOpti
 
 	var traceFlag byte
 	traceFlag = setTypeMarker(sco, traceFlag)
-	traceFlag = setSingleFlags(sco, traceFlag, scg)
+	traceFlag = setSingleFlags(ctx, sco, traceFlag, scg)
 
 	sc := spanContext{
 		childSequenceID: 0,
+		rng:             scg.rng,
 	}
 	scg.newSpanContextID(sc.id[:], traceFlag)
 
@@ -60,13 +75,13 @@ func setTypeMarker(sco SpanContextOptions, traceFlag byte) byte {
 	return traceFlag
 }
 
-func setSingleFlags(sco SpanContextOptions, traceFlag byte, scg *cachedSpanContextGenerator) byte {
+func setSingleFlags(ctx context.Context, sco SpanContextOptions, traceFlag byte, scg *cachedSpanContextGenerator) byte {
 	// 1. handle sampling flag
 	if sco.IsSampled != nil {
 		if *sco.IsSampled {
 			traceFlag |= traceFlagSampled
 		}
-	} else if scg.sampler.IsSampled(context.Background()) {
+	} else if scg.sampler.IsSampled(ctx) {
 		traceFlag |= traceFlagSampled
 	}
 
@@ -75,6 +90,19 @@ func setSingleFlags(sco SpanContextOptions, traceFlag byte, scg *cachedSpanConte
 		traceFlag |= traceFlagCritical
 	}
 
+	// 3. handle internal flag, only trusted with a type marker of the new format
+	if sco.IsInternal {
+		traceFlag |= traceFlagInternal
+		if traceFlag&typeMarkerMask == typeMarkerForOldFormat {
+			// the old debug bit is kept for the services not migrated yet
+			if traceFlag&traceFlagOldDebug == traceFlagOldDebug {
+				traceFlag |= typeMarkerForDebug
+			} else {
+				traceFlag |= typeMarkerForNormal
+			}
+		}
+	}
+
 	return traceFlag
 }
 
@@ -84,7 +112,7 @@ func (scg *cachedSpanContextGenerator) newSpanContextID(scID []byte, flag byte)
 	// 4 bytes serviceHash
 	copy(scID[:], scg.instanceIDHash[:])
 	// 6 bytes timestamp
-	timestamp := time.Now().UnixNano() / 1000
+	timestamp := scg.timeNow().UnixNano() / 1000
 	scID[4] = byte(timestamp >> 40)
 	scID[5] = byte(timestamp >> 32)
 	scID[6] = byte(timestamp >> 24)
@@ -92,17 +120,18 @@ func (scg *cachedSpanContextGenerator) newSpanContextID(scID []byte, flag byte)
 	scID[8] = byte(timestamp >> 8)
 	scID[9] = byte(timestamp)
 	// 5 bytes randomID
-	getRandomBytes(scID[10 : traceIDSize-1])
+	getRandomBytes(scg.rng, scID[entropyOffset:traceIDSize-1])
 	// 1 byte special flag
 	scID[traceIDSize-1] = flag
 
 	// Generate spanID
-	newSpanID(scID[traceIDSize:], 0, 0)
+	newSpanID(scg.rng, scID[traceIDSize:], 0, 0)
 }
 
 // GeneratorOptions are options to create a new SpanContextGenerator
 type GeneratorOptions struct {
 	sampler Sampler
+	rng     io.Reader
 }
 
 // GeneratorOption is modifier to update GeneratorOptions
@@ -115,19 +144,26 @@ func WithSampler(sampler Sampler) GeneratorOption {
 	}
 }
 
+// withRandSource sets GeneratorOptions.rng, also used by the children of the generated span contexts,
+// e.g. to generate deterministic span contexts in tests
+func withRandSource(rng io.Reader) GeneratorOption {
+	return func(options *GeneratorOptions) {
+		options.rng = rng
+	}
+}
+
 // NewSpanContextGenerator construct a SpanContextGenerator with cashed instanceID hash
+// Without serviceInstanceID, the hash is drawn from crypto/rand, like the random bytes of the ids.
+// If no sampler is given, a ProbabilisticSampler is used whose rate is read from TRACE_SAMPLE_RATE (0.001 by default).
 func NewSpanContextGenerator(serviceInstanceID string, options ...GeneratorOption) SpanContextGenerator {
-	// combine pid and timestamp as seed
-	rand.Seed((int64(os.Getpid()) << 32) + time.Now().UnixNano())
-
-	ops := GeneratorOptions{}
+	ops := GeneratorOptions{rng: rand.Reader}
 	for _, op := range options {
 		op(&ops)
 	}
 
 	var siHash [4]byte
 	if len(serviceInstanceID) == 0 {
-		rand.Read(siHash[:])
+		_, _ = io.ReadFull(ops.rng, siHash[:])
 	} else {
 		siMD5 := md5.Sum([]byte(serviceInstanceID))
 		copy(siHash[:], siMD5[md5.Size-4:])
@@ -135,13 +171,15 @@ func NewSpanContextGenerator(serviceInstanceID string, options ...GeneratorOptio
 
 	sampler := ops.sampler
 	if sampler == nil {
-		sampler = NewProbabilisticSampler(defaultSamplingProbability)
+		sampler = NewProbabilisticSampler(env.GetTraceSampleRate(defaultSamplingProbability))
 	}
 
 	return &cachedSpanContextGenerator{
 		serviceInstanceID: serviceInstanceID,
 		instanceIDHash:    siHash,
 		sampler:           sampler,
+		rng:               ops.rng,
+		timeNow:           time.Now,
 	}
 }
 
@@ -155,6 +193,8 @@ type SpanContextOptions struct {
 	// If it's nil, the default sampling strategy applies when creating new SpanContext
 	IsSampled  *bool
 	IsCritical bool
+	// IsInternal marks requests originating from internal services rather than external clients
+	IsInternal bool
 }
 
 // SpanContextOption is modifier to update SpanContextOptions
@@ -194,3 +234,10 @@ func IsCritical(isCritical bool) SpanContextOption {
 		options.IsCritical = isCritical
 	}
 }
+
+// IsInternal sets SpanContextOption.IsInternal
+func IsInternal(isInternal bool) SpanContextOption {
+	return func(options *SpanContextOptions) {
+		options.IsInternal = isInternal
+	}
+}
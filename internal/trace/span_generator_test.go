@@ -0,0 +1,208 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSpanContextGeneratorSampleRateFromEnv(t *testing.T) {
+	os.Setenv("TRACE_SAMPLE_RATE", "0.5")
+	defer os.Unsetenv("TRACE_SAMPLE_RATE")
+
+	scg := NewSpanContextGenerator("").(*cachedSpanContextGenerator)
+	sampler, ok := scg.sampler.(*ProbabilisticSampler)
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, sampler.SamplingRate())
+}
+
+func TestNewSpanContextGeneratorDefaultSampleRate(t *testing.T) {
+	os.Unsetenv("TRACE_SAMPLE_RATE")
+
+	scg := NewSpanContextGenerator("").(*cachedSpanContextGenerator)
+	sampler, ok := scg.sampler.(*ProbabilisticSampler)
+	assert.True(t, ok)
+	assert.Equal(t, defaultSamplingProbability, sampler.SamplingRate())
+}
+
+type sampleCtxKey struct{}
+
+// ctxSampler samples the trace only if the context is marked
+type ctxSampler struct{}
+
+func (s *ctxSampler) IsSampled(ctx context.Context) bool {
+	sampled, _ := ctx.Value(sampleCtxKey{}).(bool)
+	return sampled
+}
+
+func (s *ctxSampler) Close() {}
+
+func TestNewSpanContextCtxSampler(t *testing.T) {
+	scg := NewSpanContextGenerator("", WithSampler(&ctxSampler{}))
+
+	ctx := context.WithValue(context.Background(), sampleCtxKey{}, true)
+	assert.True(t, IsSpanContextSampled(scg.NewSpanContextCtx(ctx)))
+	assert.False(t, IsSpanContextSampled(scg.NewSpanContextCtx(context.Background())))
+	assert.False(t, IsSpanContextSampled(scg.NewSpanContext()))
+
+	// an explicit decision still wins over the sampler
+	sampled := false
+	assert.False(t, IsSpanContextSampled(scg.NewSpanContextCtx(ctx, IsSampled(&sampled))))
+}
+
+func TestSpanDepth(t *testing.T) {
+	root := NewSpanContextGenerator("test").NewSpanContext(IsFromStressTest(true))
+	assert.Equal(t, 0, SpanDepth(root))
+
+	sc := root
+	for depth := 1; depth <= 3; depth++ {
+		sc = sc.NewChildSpanContext()
+		assert.Equal(t, depth, SpanDepth(sc))
+	}
+
+	fromString, err := NewSpanContextFromString(sc.String())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, SpanDepth(fromString))
+	assert.Equal(t, -1, SpanDepth(nil))
+
+	// the old format is rejected
+	old := NewSpanContextGenerator("test").NewSpanContext()
+	assert.True(t, isOldFormat(old))
+	assert.Equal(t, -1, SpanDepth(old))
+	assert.Equal(t, -1, SpanDepth(old.NewChildSpanContext()))
+}
+
+func TestEntropy(t *testing.T) {
+	scg := NewSpanContextGenerator("test")
+	sc := scg.NewSpanContext(IsFromStressTest(true))
+	entropy := Entropy(sc)
+	assert.Equal(t, sc.TraceID()[10:15], entropy)
+	assert.Equal(t, entropy, Entropy(sc.NewChildSpanContext()))
+
+	// a copy is returned
+	entropy[0]++
+	assert.NotEqual(t, entropy, Entropy(sc))
+
+	assert.Nil(t, Entropy(scg.NewSpanContext()))
+	assert.Nil(t, Entropy(nil))
+}
+
+func TestIsCrossService(t *testing.T) {
+	root := NewSpanContextGenerator("test").NewSpanContext()
+	assert.False(t, IsCrossService(root))
+	assert.False(t, IsCrossService(nil))
+
+	child := root.NewChildSpanContext()
+	assert.True(t, IsCrossService(child))
+	inbound, err := NewSpanContextFromString(child.String())
+	assert.Nil(t, err)
+	assert.True(t, IsCrossService(inbound))
+
+	var id [totalIDSize]byte
+	copy(id[:], child.Bytes())
+	copy(id[traceIDSize+spanIDSize:], child.SpanID())
+	self, err := NewSpanContextFromBytes(id[:])
+	assert.Nil(t, err)
+	assert.False(t, IsCrossService(self))
+}
+
+func sequenceID(sc SpanContext) uint16 {
+	return uint16(sc.SpanID()[1])<<8 | uint16(sc.SpanID()[2])
+}
+
+func TestForkN(t *testing.T) {
+	root := NewSpanContextGenerator("test").NewSpanContext(IsFromStressTest(true))
+	root.NewChildSpanContext()
+
+	children := ForkN(root, 3)
+	assert.Equal(t, 3, len(children))
+	for i, child := range children {
+		assert.Equal(t, uint16(i+1), sequenceID(child))
+		assert.Equal(t, 1, SpanDepth(child))
+		assert.Equal(t, root.SpanID(), child.ParentID())
+	}
+	assert.Equal(t, uint16(4), sequenceID(root.NewChildSpanContext()))
+	assert.Nil(t, ForkN(nil, 3))
+	assert.Nil(t, ForkN(root, 0))
+}
+
+func TestForkNConcurrent(t *testing.T) {
+	root := NewSpanContextGenerator("test").NewSpanContext()
+
+	const goroutines, n = 50, 10
+	forks := make([][]SpanContext, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			forks[g] = ForkN(root, n)
+		}(g)
+	}
+	wg.Wait()
+
+	seen := map[uint16]bool{}
+	for _, children := range forks {
+		first := sequenceID(children[0])
+		for i, child := range children {
+			// contiguous within a fork
+			assert.Equal(t, first+uint16(i), sequenceID(child))
+			assert.False(t, seen[sequenceID(child)])
+			seen[sequenceID(child)] = true
+		}
+	}
+	assert.Equal(t, goroutines*n, len(seen))
+}
+
+func TestNewSpanContextGeneratorCollisions(t *testing.T) {
+	// many instances without instance id creating traces at the same time
+	const instances, perInstance = 200, 500
+	seen := make(map[string]struct{}, instances*perInstance)
+	for i := 0; i < instances; i++ {
+		generator := NewSpanContextGenerator("")
+		for j := 0; j < perInstance; j++ {
+			seen[string(generator.NewSpanContext().TraceID())] = struct{}{}
+		}
+	}
+	assert.Equal(t, instances*perInstance, len(seen))
+}
+
+func TestGetRandomBytesNotAllZero(t *testing.T) {
+	bs := []byte{9, 9, 9, 9, 9}
+	getRandomBytes(bytes.NewReader(make([]byte, 5)), bs)
+	assert.Equal(t, []byte{0, 0, 0, 0, 1}, bs)
+
+	sc := NewSpanContextGenerator("test", withRandSource(bytes.NewReader(make([]byte, 64)))).NewSpanContext()
+	assert.NotEqual(t, zeroSpanID[:], sc.SpanID())
+
+	// a zero last byte is kept when another byte is not zero
+	getRandomBytes(bytes.NewReader([]byte{0, 0, 7, 0, 0}), bs)
+	assert.Equal(t, []byte{0, 0, 7, 0, 0}, bs)
+}
+
+func TestNewSpanContextDeterministic(t *testing.T) {
+	newSpanContext := func() SpanContext {
+		generator := NewSpanContextGenerator("", withRandSource(rand.New(rand.NewSource(1)))).(*cachedSpanContextGenerator)
+		generator.timeNow = func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }
+		sampled := true
+		return generator.NewSpanContext(IsSampled(&sampled))
+	}
+
+	sc := newSpanContext()
+	// instance hash, timestamp, random bytes and sampled flag : span id : no parent
+	assert.Equal(t, "52fdfc07"+"19c8c8022000"+"2182654f16"+"02"+":0000003f5f0f9a62:0000000000000000", sc.String())
+	assert.Equal(t, sc.String(), newSpanContext().String())
+
+	// the children use the rng of the generator too
+	child := sc.NewChildSpanContext()
+	grandchild := child.NewChildSpanContext()
+	forked := ForkN(newSpanContext(), 1)[0]
+	assert.Equal(t, child.String(), forked.String())
+	assert.Equal(t, grandchild.String(), forked.NewChildSpanContext().String())
+}
@@ -2,12 +2,23 @@ package env
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 )
 
+var (
+	// hostname is replaced in tests.
+	hostname = os.Hostname
+
+	generatedInstanceDir     string
+	generatedInstanceDirOnce sync.Once
+)
+
 func GetEnv() string {
 	val, ok := os.LookupEnv("ENV")
 	if !ok {
@@ -28,25 +39,86 @@ func IsSzK8S() bool {
 	return false
 }
 
+// IsK8S returns true when running in a Kubernetes pod, of any flavor.
+func IsK8S() bool {
+	_, ok := os.LookupEnv("KUBERNETES_SERVICE_HOST")
+	return ok
+}
+
+func isNomad() bool {
+	_, ok := os.LookupEnv("NOMAD_ALLOC_ID")
+	return ok
+}
+
+// InstanceID returns the best available identifier of this instance:
+// POD_NAME, NOMAD_ALLOC_ID or the hostname, in that order, or "" if none is available.
+func InstanceID() string {
+	for _, key := range []string{"POD_NAME", "NOMAD_ALLOC_ID"} {
+		if val, ok := os.LookupEnv(key); ok && val != "" {
+			return val
+		}
+	}
+	name, _ := hostname()
+	return name
+}
+
+// getGeneratedInstanceDir returns a directory name generated from the time, once per process,
+// for the instances without any identifier.
+func getGeneratedInstanceDir() string {
+	generatedInstanceDirOnce.Do(func() {
+		rand.Seed(time.Now().UnixNano() ^ int64(os.Getpid()))
+		generatedInstanceDir = fmt.Sprintf("%s-%d", time.Now().Format("20060102150405"), rand.Int()%1000)
+	})
+	return generatedInstanceDir
+}
+
 func IsSplitLog() bool {
 	_, ok := os.LookupEnv("SPLIT_LOG")
 	return ok
 }
 
+// GetRegion returns the deployment region from REGION, falling back to DATACENTER.
+func GetRegion() string {
+	if val, ok := os.LookupEnv("REGION"); ok && val != "" {
+		return val
+	}
+	return os.Getenv("DATACENTER")
+}
+
+// GetTraceSampleRate returns the sampling probability set by TRACE_SAMPLE_RATE, clamped to [0,1].
+// defaultRate is returned if the variable is not set or can't be parsed.
+func GetTraceSampleRate(defaultRate float64) float64 {
+	val, ok := os.LookupEnv("TRACE_SAMPLE_RATE")
+	if !ok {
+		return defaultRate
+	}
+	rate, err := strconv.ParseFloat(val, 64)
+	if err != nil || math.IsNaN(rate) {
+		return defaultRate
+	}
+	return math.Max(0.0, math.Min(rate, 1.0))
+}
+
+// GetLogDir returns the default log directory, set by LOG_DIR, ./log if not set.
+func GetLogDir() string {
+	if val, ok := os.LookupEnv("LOG_DIR"); ok && val != "" {
+		return val
+	}
+	return "./log"
+}
+
+// GetFilePath returns the path of the log file named filename.
+// The directory is logDir if given, otherwise GetLogDir, i.e. logDir > LOG_DIR > ./log.
 func GetFilePath(logDir, filename string) string {
 	if logDir == "" {
-		logDir = "./log"
+		logDir = GetLogDir()
 	}
 	path := filepath.Join(logDir, fmt.Sprintf("%s.log", filename))
-	if IsSzK8S() {
-		dir := ""
-		podName, ok := os.LookupEnv("POD_NAME")
-		if ok {
-			dir = podName
-		}
+	// Instances of an orchestrator may share the log volume, so each writes into its own directory.
+	if IsSzK8S() || IsK8S() || isNomad() {
+		dir := InstanceID()
 		if dir == "" {
-			rand.Seed(time.Now().UnixNano() ^ int64(os.Getpid()))
-			dir = fmt.Sprintf("%s-%d", time.Now().Format("20060102150405"), rand.Int()%1000)
+			dir = getGeneratedInstanceDir()
 		}
 		path = filepath.Join(logDir, dir, fmt.Sprintf("%s.log", filename))
 	}
@@ -1,16 +1,28 @@
 package env
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// clearInstanceEnv unsets the env read to detect the orchestrator and the instance, until the end of the test.
+func clearInstanceEnv(t *testing.T) {
+	for _, key := range []string{"ORCHESTRATOR", "KUBERNETES_SERVICE_HOST", "POD_NAME", "NOMAD_ALLOC_ID", "LOG_DIR"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
 // test dir nil
 func TestGetFilePath(t *testing.T) {
+	clearInstanceEnv(t)
 	var dir string
 	path := GetFilePath(dir, "server")
 	fmt.Println(path)
@@ -19,8 +31,9 @@ func TestGetFilePath(t *testing.T) {
 
 // test dir nil and sz k8s
 func TestGetFilePathWithSzK8s(t *testing.T) {
+	clearInstanceEnv(t)
 	var dir string
-	os.Setenv("ORCHESTRATOR", "sz-kubernetes")
+	t.Setenv("ORCHESTRATOR", "sz-kubernetes")
 
 	path := GetFilePath(dir, "server")
 	fmt.Println(path)
@@ -28,13 +41,14 @@ func TestGetFilePathWithSzK8s(t *testing.T) {
 	assert.Equal(t, "log", ss[0])
 	assert.Equal(t, "server.log", ss[2])
 
-	os.Setenv("POD_NAME", "test-podname")
+	t.Setenv("POD_NAME", "test-podname")
 	path = GetFilePath(dir, "server")
 	fmt.Println(path)
 	assert.Equal(t, "log/test-podname/server.log", path)
 }
 
 func TestGetFilePathWithDir(t *testing.T) {
+	clearInstanceEnv(t)
 	dir := "./test"
 	path := GetFilePath(dir, "server")
 	fmt.Println(path)
@@ -42,8 +56,9 @@ func TestGetFilePathWithDir(t *testing.T) {
 }
 
 func TestGetFilePathWithDirSzK8s(t *testing.T) {
+	clearInstanceEnv(t)
 	dir := "./test"
-	os.Setenv("ORCHESTRATOR", "sz-kubernetes")
+	t.Setenv("ORCHESTRATOR", "sz-kubernetes")
 
 	path := GetFilePath(dir, "server")
 	fmt.Println(path)
@@ -51,8 +66,107 @@ func TestGetFilePathWithDirSzK8s(t *testing.T) {
 	assert.Equal(t, "test", ss[0])
 	assert.Equal(t, "server.log", ss[2])
 
-	os.Setenv("POD_NAME", "test-podname")
+	t.Setenv("POD_NAME", "test-podname")
 	path = GetFilePath(dir, "server")
 	fmt.Println(path)
 	assert.Equal(t, "test/test-podname/server.log", path)
 }
+
+func TestGetFilePathWithLogDir(t *testing.T) {
+	clearInstanceEnv(t)
+	t.Setenv("LOG_DIR", "/var/log/app")
+	assert.Equal(t, "/var/log/app", GetLogDir())
+	assert.Equal(t, "/var/log/app/server.log", GetFilePath("", "server"))
+	assert.Equal(t, "test/server.log", GetFilePath("./test", "server"))
+
+	t.Setenv("POD_NAME", "pod-1")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	assert.Equal(t, "/var/log/app/pod-1/server.log", GetFilePath("", "server"))
+
+	t.Setenv("LOG_DIR", "")
+	assert.Equal(t, "./log", GetLogDir())
+	assert.Equal(t, "log/pod-1/server.log", GetFilePath("", "server"))
+}
+
+func TestGetFilePathGeneratedInstanceDir(t *testing.T) {
+	clearInstanceEnv(t)
+	t.Setenv("ORCHESTRATOR", "sz-kubernetes")
+	oldHostname := hostname
+	hostname = func() (string, error) { return "", errors.New("no hostname") }
+	defer func() { hostname = oldHostname }()
+
+	server := GetFilePath("", "server")
+	time.Sleep(time.Second)
+	errorLog := GetFilePath("", "error")
+	assert.Equal(t, filepath.Dir(server), filepath.Dir(errorLog))
+	assert.Equal(t, "error.log", filepath.Base(errorLog))
+	assert.NotEqual(t, "log", filepath.Dir(server))
+}
+
+func TestIsK8S(t *testing.T) {
+	clearInstanceEnv(t)
+	assert.False(t, IsK8S())
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	assert.True(t, IsK8S())
+	assert.False(t, IsSzK8S())
+}
+
+func TestInstanceID(t *testing.T) {
+	clearInstanceEnv(t)
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, InstanceID())
+
+	t.Setenv("NOMAD_ALLOC_ID", "alloc-1")
+	assert.Equal(t, "alloc-1", InstanceID())
+
+	t.Setenv("POD_NAME", "pod-1")
+	assert.Equal(t, "pod-1", InstanceID())
+
+	t.Setenv("POD_NAME", "")
+	assert.Equal(t, "alloc-1", InstanceID())
+}
+
+func TestGetFilePathWithInstance(t *testing.T) {
+	hostname, _ := os.Hostname()
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"none", nil, "log/server.log"},
+		{"k8s hostname", map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, filepath.Join("log", hostname, "server.log")},
+		{"k8s pod", map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1", "POD_NAME": "pod-1"}, "log/pod-1/server.log"},
+		{"sz k8s pod", map[string]string{"ORCHESTRATOR": "sz-kubernetes", "POD_NAME": "pod-1"}, "log/pod-1/server.log"},
+		{"nomad", map[string]string{"NOMAD_ALLOC_ID": "alloc-1"}, "log/alloc-1/server.log"},
+		{"pod over nomad", map[string]string{"NOMAD_ALLOC_ID": "alloc-1", "POD_NAME": "pod-1"}, "log/pod-1/server.log"},
+		{"pod name only", map[string]string{"POD_NAME": "pod-1"}, "log/server.log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearInstanceEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			assert.Equal(t, tt.want, GetFilePath("", "server"))
+		})
+	}
+}
+
+func TestGetTraceSampleRate(t *testing.T) {
+	os.Unsetenv("TRACE_SAMPLE_RATE")
+	assert.Equal(t, 0.001, GetTraceSampleRate(0.001))
+
+	os.Setenv("TRACE_SAMPLE_RATE", "0.25")
+	assert.Equal(t, 0.25, GetTraceSampleRate(0.001))
+
+	os.Setenv("TRACE_SAMPLE_RATE", "3")
+	assert.Equal(t, 1.0, GetTraceSampleRate(0.001))
+
+	os.Setenv("TRACE_SAMPLE_RATE", "-1")
+	assert.Equal(t, 0.0, GetTraceSampleRate(0.001))
+
+	os.Setenv("TRACE_SAMPLE_RATE", "abc")
+	assert.Equal(t, 0.001, GetTraceSampleRate(0.001))
+	os.Unsetenv("TRACE_SAMPLE_RATE")
+}
@@ -0,0 +1,44 @@
+package writer
+
+import (
+	"io"
+	"time"
+)
+
+// NewDirectWriter returns a BufferedWriter writing each write into w right away, without buffer,
+// for the logs which must be persisted as soon as they are written.
+func NewDirectWriter(w io.Writer) BufferedWriter {
+	return &directWriter{wr: w}
+}
+
+type directWriter struct {
+	wr    io.Writer
+	stats writerStats
+}
+
+func (d *directWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := d.wr.Write(p)
+	d.stats.blockedNanos.Add(int64(time.Since(start)))
+	d.stats.bytesWritten.Add(uint64(n))
+	if err != nil {
+		d.stats.flushErrors.Add(1)
+	} else {
+		d.stats.flushes.Add(1)
+	}
+	return n, err
+}
+
+// Flush does nothing, the writes are never buffered.
+func (d *directWriter) Flush() error {
+	return nil
+}
+
+func (d *directWriter) Stats() Stats {
+	return Stats{
+		BytesWritten: d.stats.bytesWritten.Load(),
+		Flushes:      d.stats.flushes.Load(),
+		FlushErrors:  d.stats.flushErrors.Load(),
+		BlockedTime:  time.Duration(d.stats.blockedNanos.Load()),
+	}
+}
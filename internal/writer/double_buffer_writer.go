@@ -3,18 +3,28 @@ package writer
 import (
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 func NewDoubleBufWriterSize(w io.Writer, size int) BufferedWriter {
+	return NewDoubleBufWriterSizePeriod(w, size, defaultFlushPeriod)
+}
+
+// NewDoubleBufWriterSizePeriod 与NewDoubleBufWriterSize相同，但每隔period自动同步一次缓存
+func NewDoubleBufWriterSizePeriod(w io.Writer, size int, period time.Duration) BufferedWriter {
 	if size <= 0 {
 		size = defaultBufSize
 	}
+	if period <= 0 {
+		period = defaultFlushPeriod
+	}
 	wr := &doubleBufferWriter{
 		master: make([]byte, size),
 		slave:  make([]byte, size),
 		wr:     w,
 		size:   size,
+		period: period,
 		done:   make(chan struct{}),
 		sync:   make(chan struct{}, 1),
 		cond:   sync.NewCond(&sync.Mutex{}),
@@ -29,7 +39,9 @@ slave   -- 副缓存，用于同步数据
 n       -- 当前主缓存写位置
 p、q     -- 副缓存同步数据的起始和结束位置
 size     -- 缓存大小
+period   -- 定时同步的间隔
 wr       -- 底层写接口
+stats    -- 统计数据
 */
 type doubleBufferWriter struct {
 	master []byte
@@ -38,6 +50,7 @@ type doubleBufferWriter struct {
 	p      int
 	q      int
 	size   int
+	period time.Duration
 	err    error
 	wr     io.Writer
 	done   chan struct{}
@@ -45,6 +58,14 @@ type doubleBufferWriter struct {
 	cond   *sync.Cond
 	guard  sync.Mutex
 	closed bool
+	stats  writerStats
+}
+
+type writerStats struct {
+	bytesWritten atomic.Uint64
+	flushes      atomic.Uint64
+	flushErrors  atomic.Uint64
+	blockedNanos atomic.Int64
 }
 
 // 同步副缓存。因为Write返回的可能小于master的长度，因而用p、q分别标识写入的起始和结束位置，当p、q相等时表示同步完成
@@ -60,9 +81,12 @@ func (b *doubleBufferWriter) flush() error {
 		err = io.ErrShortWrite
 	}
 	b.p += n
+	b.stats.bytesWritten.Add(uint64(n))
 	if err != nil {
+		b.stats.flushErrors.Add(1)
 		return err
 	}
+	b.stats.flushes.Add(1)
 	b.cond.Signal()
 	return nil
 }
@@ -127,6 +151,7 @@ func (b *doubleBufferWriter) Write(p []byte) (nn int, err error) {
 		b.guard.Unlock()
 
 		if full {
+			start := time.Now()
 			b.cond.L.Lock()
 			synced := b.p == b.q
 			b.cond.L.Unlock()
@@ -138,6 +163,7 @@ func (b *doubleBufferWriter) Write(p []byte) (nn int, err error) {
 				}
 				b.cond.L.Unlock()
 			}
+			b.stats.blockedNanos.Add(int64(time.Since(start)))
 			b.swap()
 			b.sync <- struct{}{}
 		}
@@ -145,8 +171,18 @@ func (b *doubleBufferWriter) Write(p []byte) (nn int, err error) {
 	return nn, nil
 }
 
+// Stats 返回统计数据，阻塞时间为主缓存写满后等待副缓存同步完成的时间
+func (b *doubleBufferWriter) Stats() Stats {
+	return Stats{
+		BytesWritten: b.stats.bytesWritten.Load(),
+		Flushes:      b.stats.flushes.Load(),
+		FlushErrors:  b.stats.flushErrors.Load(),
+		BlockedTime:  time.Duration(b.stats.blockedNanos.Load()),
+	}
+}
+
 func (b *doubleBufferWriter) flushPeriodically() {
-	ticker := time.NewTicker(defaultFlushPeriod)
+	ticker := time.NewTicker(b.period)
 	defer ticker.Stop()
 	for {
 		select {
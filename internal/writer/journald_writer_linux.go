@@ -0,0 +1,76 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JournaldSocket is the socket of the native protocol of journald.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldWriter sends entries to journald with its native protocol, one datagram per entry.
+type JournaldWriter struct {
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// NewJournaldWriter connects to the journald socket at path, see JournaldSocket.
+func NewJournaldWriter(path string) (*JournaldWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldWriter{conn: conn}, nil
+}
+
+// WriteEntry sends one entry of the syslog priority (0 emerg to 7 debug) and the variables vars,
+// whose keys must be journald field names, e.g. MESSAGE.
+func (w *JournaldWriter) WriteEntry(priority int, vars map[string]string) error {
+	var b bytes.Buffer
+	appendJournaldVar(&b, "PRIORITY", strconv.Itoa(priority))
+	for k, v := range vars {
+		appendJournaldVar(&b, k, v)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.conn.Write(b.Bytes())
+	return err
+}
+
+// appendJournaldVar appends KEY=value, or for a value of several lines, KEY, its length and the value.
+func appendJournaldVar(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// Write sends p as the message of an entry of priority info.
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	if err := w.WriteEntry(6, map[string]string{"MESSAGE": strings.TrimSuffix(string(p), "\n")}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *JournaldWriter) Sync() error {
+	return nil
+}
+
+func (w *JournaldWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn.Close()
+}
@@ -0,0 +1,121 @@
+package writer
+
+import (
+	"sync/atomic"
+)
+
+// Producer sends messages to a Kafka topic, it is implemented by the user with the Kafka client of their choice.
+type Producer interface {
+	Produce(topic string, value []byte) error
+	Close() error
+}
+
+// KafkaWriter hands each write to the producer from a background go-routine.
+// Writes are queued in a bounded queue and dropped when it is full by default, so logging never blocks on Kafka.
+type KafkaWriter struct {
+	producer Producer
+	topic    string
+	queue    *Queue[[]byte]
+	flush    chan chan struct{}
+	done     chan struct{}
+	exited   chan struct{}
+	closed   atomic.Bool
+	errors   atomic.Uint64
+}
+
+// NewKafkaWriter returns a writer sending each write as one message to topic, at most queueSize messages are buffered,
+// 10000 if queueSize is not positive. Messages are dropped when the queue is full.
+func NewKafkaWriter(producer Producer, topic string, queueSize int) *KafkaWriter {
+	return NewKafkaWriterPolicy(producer, topic, queueSize, QueueDrop)
+}
+
+// NewKafkaWriterPolicy is NewKafkaWriter with the policy applied when the queue is full.
+func NewKafkaWriterPolicy(producer Producer, topic string, queueSize int, policy QueueFullPolicy) *KafkaWriter {
+	w := &KafkaWriter{
+		producer: producer,
+		topic:    topic,
+		queue:    NewQueue[[]byte](queueSize, policy),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *KafkaWriter) run() {
+	defer close(w.exited)
+	for {
+		select {
+		case msg := <-w.queue.C():
+			w.produce(msg)
+		case ack := <-w.flush:
+			w.drain()
+			close(ack)
+		case <-w.done:
+			w.drain()
+			return
+		}
+	}
+}
+
+func (w *KafkaWriter) drain() {
+	for {
+		select {
+		case msg := <-w.queue.C():
+			w.produce(msg)
+		default:
+			return
+		}
+	}
+}
+
+func (w *KafkaWriter) produce(msg []byte) {
+	if err := w.producer.Produce(w.topic, msg); err != nil {
+		w.errors.Add(1)
+	}
+}
+
+// Write queues a copy of p, as the caller may reuse it, applying the policy if the queue is full.
+func (w *KafkaWriter) Write(p []byte) (int, error) {
+	msg := make([]byte, len(p))
+	copy(msg, p)
+	w.queue.Push(msg)
+	return len(p), nil
+}
+
+// Sync waits until the queued messages are handed to the producer.
+func (w *KafkaWriter) Sync() error {
+	if w.closed.Load() {
+		return nil
+	}
+	ack := make(chan struct{})
+	select {
+	case w.flush <- ack:
+		<-ack
+	case <-w.done:
+	}
+	return nil
+}
+
+// Close sends the queued messages and closes the producer.
+func (w *KafkaWriter) Close() error {
+	if !w.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	w.queue.Close()
+	close(w.done)
+	<-w.exited
+	return w.producer.Close()
+}
+
+// Dropped returns the number of messages dropped because the queue was full or the writer was closed.
+func (w *KafkaWriter) Dropped() uint64 {
+	stats := w.queue.Stats()
+	return stats.Dropped + stats.DroppedOldest
+}
+
+// QueueStats returns the counters of the queue.
+func (w *KafkaWriter) QueueStats() QueueStats {
+	return w.queue.Stats()
+}
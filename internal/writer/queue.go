@@ -0,0 +1,134 @@
+package writer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueueFullPolicy is what a Queue does with a new item when it is full.
+type QueueFullPolicy int
+
+const (
+	// QueueDrop drops the new item.
+	QueueDrop QueueFullPolicy = iota
+	// QueueBlock waits until there is room for the new item.
+	QueueBlock
+	// QueueDropOldest drops the oldest queued item to make room for the new one.
+	QueueDropOldest
+)
+
+// QueueStats are the counters of a Queue.
+type QueueStats struct {
+	// Enqueued is the number of items queued.
+	Enqueued uint64
+	// Dropped is the number of new items dropped, by QueueDrop or because the queue was closed.
+	Dropped uint64
+	// DroppedOldest is the number of queued items dropped by QueueDropOldest.
+	DroppedOldest uint64
+	// Blocked is the number of pushes which waited for room by QueueBlock.
+	Blocked uint64
+	// BlockedTime is the time spent waiting for room by QueueBlock.
+	BlockedTime time.Duration
+}
+
+// Queue is a bounded FIFO queue read by one consumer, which applies its QueueFullPolicy when it is full.
+type Queue[T any] struct {
+	items     chan T
+	policy    QueueFullPolicy
+	done      chan struct{}
+	closeOnce sync.Once
+
+	enqueued      atomic.Uint64
+	dropped       atomic.Uint64
+	droppedOldest atomic.Uint64
+	blocked       atomic.Uint64
+	blockedTime   atomic.Int64
+}
+
+// NewQueue returns a queue of at most size items.
+func NewQueue[T any](size int, policy QueueFullPolicy) *Queue[T] {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	return &Queue[T]{
+		items:  make(chan T, size),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+}
+
+// Push queues item according to the policy, and returns false if it was dropped.
+func (q *Queue[T]) Push(item T) bool {
+	// checked first, as a select picks any ready case
+	select {
+	case <-q.done:
+		q.dropped.Add(1)
+		return false
+	default:
+	}
+	select {
+	case q.items <- item:
+		q.enqueued.Add(1)
+		return true
+	default:
+	}
+
+	switch q.policy {
+	case QueueBlock:
+		q.blocked.Add(1)
+		start := time.Now()
+		defer func() {
+			q.blockedTime.Add(int64(time.Since(start)))
+		}()
+		select {
+		case q.items <- item:
+			q.enqueued.Add(1)
+			return true
+		case <-q.done:
+			q.dropped.Add(1)
+			return false
+		}
+	case QueueDropOldest:
+		for {
+			select {
+			case q.items <- item:
+				q.enqueued.Add(1)
+				return true
+			default:
+			}
+			select {
+			case <-q.items:
+				q.droppedOldest.Add(1)
+			default:
+			}
+		}
+	default:
+		q.dropped.Add(1)
+		return false
+	}
+}
+
+// C returns the channel the consumer reads the items from.
+func (q *Queue[T]) C() <-chan T {
+	return q.items
+}
+
+// Close makes the following pushes drop their item, and releases the blocked ones.
+// The items already queued can still be read.
+func (q *Queue[T]) Close() {
+	q.closeOnce.Do(func() {
+		close(q.done)
+	})
+}
+
+// Stats returns the counters of the queue.
+func (q *Queue[T]) Stats() QueueStats {
+	return QueueStats{
+		Enqueued:      q.enqueued.Load(),
+		Dropped:       q.dropped.Load(),
+		DroppedOldest: q.droppedOldest.Load(),
+		Blocked:       q.blocked.Load(),
+		BlockedTime:   time.Duration(q.blockedTime.Load()),
+	}
+}
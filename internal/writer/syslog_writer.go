@@ -0,0 +1,139 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// syslogFacility is the facility of the messages, user
+	syslogFacility = 1
+	// SeverityInfo is the severity of the messages sent by Write
+	SeverityInfo = 6
+
+	syslogDialTimeout = 5 * time.Second
+	// defaultSyslogRedialInterval is the minimum interval between two dials once syslog can't be reached
+	defaultSyslogRedialInterval = time.Second
+)
+
+var errSyslogUnreachable = errors.New("syslog unreachable, waiting to dial again")
+
+// SyslogWriter sends each write as one RFC 3164 syslog message.
+// When a write fails, e.g. the daemon restarted or dropped the TCP connection,
+// it dials again and resends the message, and keeps dialing on the next writes until syslog is back.
+type SyslogWriter struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	conn     net.Conn // nil while syslog can't be reached
+	closed   bool
+	hostname string
+	tag      string
+
+	redialInterval time.Duration
+	redialAt       time.Time
+}
+
+// NewSyslogWriter connects to the syslog daemon at addr, e.g. udp://127.0.0.1:514, tcp://127.0.0.1:514 or unix:///dev/log,
+// messages are tagged with tag.
+func NewSyslogWriter(addr, tag string) (*SyslogWriter, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	network, address := u.Scheme, u.Host
+	if network == "unix" || network == "unixgram" {
+		address = u.Path
+	}
+	if network == "" || address == "" {
+		return nil, fmt.Errorf("invalid syslog address %q", addr)
+	}
+	conn, err := net.DialTimeout(network, address, syslogDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return &SyslogWriter{
+		network:        network,
+		address:        address,
+		conn:           conn,
+		hostname:       hostname,
+		tag:            tag,
+		redialInterval: defaultSyslogRedialInterval,
+	}, nil
+}
+
+// Write sends p as a message of severity info.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteSeverity(SeverityInfo, p)
+}
+
+// WriteSeverity sends p as a message of the severity, from 0 (emerg) to 7 (debug).
+// It returns an error if the message can't be sent even after dialing again, net.ErrClosed once the writer is closed.
+func (w *SyslogWriter) WriteSeverity(severity int, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, net.ErrClosed
+	}
+	msg := fmt.Sprintf("<%d>%s %s %s[%d]: %s\n",
+		syslogFacility<<3|severity, time.Now().Format(time.Stamp), w.hostname, w.tag, os.Getpid(),
+		strings.TrimSuffix(string(p), "\n"))
+	if w.conn != nil {
+		if _, err := io.WriteString(w.conn, msg); err == nil {
+			return len(p), nil
+		}
+		// the connection is broken, dial again right away
+		_ = w.conn.Close()
+		w.conn = nil
+		w.redialAt = time.Time{}
+	}
+	if err := w.redial(); err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(w.conn, msg); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		w.redialAt = time.Now().Add(w.redialInterval)
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redial - Dial syslog again, at most once per redialInterval.
+func (w *SyslogWriter) redial() error {
+	if time.Now().Before(w.redialAt) {
+		return errSyslogUnreachable
+	}
+	conn, err := net.DialTimeout(w.network, w.address, syslogDialTimeout)
+	if err != nil {
+		w.redialAt = time.Now().Add(w.redialInterval)
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *SyslogWriter) Sync() error {
+	return nil
+}
+
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
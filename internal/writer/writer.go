@@ -5,9 +5,33 @@ import "time"
 const (
 	defaultBufSize     = 4096
 	defaultFlushPeriod = 10 * time.Millisecond
+	defaultQueueSize   = 10000
 )
 
 type BufferedWriter interface {
 	Write(p []byte) (n int, err error)
 	Flush() error
+	Stats() Stats
+}
+
+// Stats are the counters of a BufferedWriter.
+type Stats struct {
+	// BytesWritten is the number of bytes written to the underlying writer.
+	BytesWritten uint64
+	// Flushes is the number of successful writes to the underlying writer.
+	Flushes uint64
+	// FlushErrors is the number of failed writes to the underlying writer.
+	FlushErrors uint64
+	// BlockedTime is the time Write spent waiting for the underlying writer.
+	BlockedTime time.Duration
+}
+
+// Add returns the sum of s and o.
+func (s Stats) Add(o Stats) Stats {
+	return Stats{
+		BytesWritten: s.BytesWritten + o.BytesWritten,
+		Flushes:      s.Flushes + o.Flushes,
+		FlushErrors:  s.FlushErrors + o.FlushErrors,
+		BlockedTime:  s.BlockedTime + o.BlockedTime,
+	}
 }
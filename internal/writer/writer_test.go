@@ -1,10 +1,16 @@
 package writer
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 const minReadBufferSize = 16
@@ -63,3 +69,267 @@ func TestWriters(t *testing.T) {
 		}
 	}
 }
+
+// syncBuffer is a bytes.Buffer safe for concurrent use by the flushing goroutine and the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestFlushPeriod(t *testing.T) {
+	w := &syncBuffer{}
+	buf := NewDoubleBufWriterSizePeriod(w, 1024, 50*time.Millisecond)
+	defer buf.Flush()
+
+	n, err := buf.Write([]byte("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("buf.Write = %d, %v", n, err)
+	}
+	if got := w.String(); got != "" {
+		t.Errorf("flushed before the period: %q", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for w.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := w.String(); got != "hello" {
+		t.Errorf("not flushed after the period: %q", got)
+	}
+}
+
+// slowWriter simulates a downstream file writer which blocks
+type slowWriter struct {
+	delay time.Duration
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return len(p), nil
+}
+
+func TestStatsBlockedTime(t *testing.T) {
+	buf := NewDoubleBufWriterSizePeriod(&slowWriter{delay: 20 * time.Millisecond}, 16, time.Hour)
+
+	data := make([]byte, 16)
+	for i := 0; i < 5; i++ {
+		if _, err := buf.Write(data); err != nil {
+			t.Fatalf("buf.Write = %v", err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		t.Fatalf("buf.Flush = %v", err)
+	}
+
+	stats := buf.Stats()
+	if stats.BlockedTime <= 0 {
+		t.Errorf("blocked time = %v, want > 0", stats.BlockedTime)
+	}
+	if stats.BytesWritten != 80 {
+		t.Errorf("bytes written = %d, want 80", stats.BytesWritten)
+	}
+	if stats.Flushes == 0 || stats.FlushErrors != 0 {
+		t.Errorf("flushes = %d, flush errors = %d", stats.Flushes, stats.FlushErrors)
+	}
+}
+
+type blockingProducer struct {
+	// started, if not nil, receives when the first message is produced
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	count   int
+}
+
+func (p *blockingProducer) Produce(topic string, value []byte) error {
+	select {
+	case p.started <- struct{}{}:
+	default:
+	}
+	<-p.release
+	p.mu.Lock()
+	p.count++
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *blockingProducer) Close() error { return nil }
+
+func TestKafkaWriterDropOnFull(t *testing.T) {
+	producer := &blockingProducer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	w := NewKafkaWriter(producer, "logs", 2)
+
+	// one message is held by the blocked producer, two are queued, the others are dropped
+	if _, err := w.Write([]byte("msg")); err != nil {
+		t.Fatalf("w.Write = %v", err)
+	}
+	<-producer.started
+	for i := 1; i < 10; i++ {
+		if _, err := w.Write([]byte("msg")); err != nil {
+			t.Fatalf("w.Write = %v", err)
+		}
+	}
+	close(producer.release)
+	if err := w.Close(); err != nil {
+		t.Fatalf("w.Close = %v", err)
+	}
+
+	if producer.count+int(w.Dropped()) != 10 {
+		t.Errorf("produced %d, dropped %d, want 10 in total", producer.count, w.Dropped())
+	}
+	if producer.count != 3 {
+		t.Errorf("produced %d, want 3", producer.count)
+	}
+}
+
+func TestQueueFullPolicies(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		q := NewQueue[int](2, QueueDrop)
+		for i := 0; i < 5; i++ {
+			q.Push(i)
+		}
+		if got := []int{<-q.C(), <-q.C()}; got[0] != 0 || got[1] != 1 {
+			t.Errorf("queued %v, want [0 1]", got)
+		}
+		if s := q.Stats(); s.Enqueued != 2 || s.Dropped != 3 {
+			t.Errorf("stats = %+v, want 2 enqueued and 3 dropped", s)
+		}
+	})
+
+	t.Run("drop-oldest", func(t *testing.T) {
+		q := NewQueue[int](2, QueueDropOldest)
+		for i := 0; i < 5; i++ {
+			q.Push(i)
+		}
+		if got := []int{<-q.C(), <-q.C()}; got[0] != 3 || got[1] != 4 {
+			t.Errorf("queued %v, want [3 4]", got)
+		}
+		if s := q.Stats(); s.Enqueued != 5 || s.DroppedOldest != 3 || s.Dropped != 0 {
+			t.Errorf("stats = %+v, want 5 enqueued and 3 oldest dropped", s)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		q := NewQueue[int](2, QueueBlock)
+		q.Push(0)
+		q.Push(1)
+		pushed := make(chan bool)
+		go func() {
+			pushed <- q.Push(2)
+		}()
+		select {
+		case <-pushed:
+			t.Fatal("push on a full queue didn't block")
+		case <-time.After(20 * time.Millisecond):
+		}
+		<-q.C()
+		if !<-pushed {
+			t.Error("blocked push was dropped")
+		}
+		if s := q.Stats(); s.Enqueued != 3 || s.Blocked != 1 || s.BlockedTime <= 0 {
+			t.Errorf("stats = %+v, want 3 enqueued and 1 blocked", s)
+		}
+
+		go func() {
+			pushed <- q.Push(3)
+		}()
+		time.Sleep(time.Millisecond)
+		q.Close()
+		if <-pushed {
+			t.Error("push blocked on a closed queue wasn't dropped")
+		}
+		if s := q.Stats(); s.Dropped != 1 {
+			t.Errorf("stats = %+v, want 1 dropped", s)
+		}
+	})
+}
+
+func TestKafkaWriterBlockOnFull(t *testing.T) {
+	producer := &blockingProducer{release: make(chan struct{})}
+	w := NewKafkaWriterPolicy(producer, "logs", 2, QueueBlock)
+
+	written := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			_, _ = w.Write([]byte("msg"))
+		}
+		close(written)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(producer.release)
+	<-written
+	if err := w.Close(); err != nil {
+		t.Fatalf("w.Close = %v", err)
+	}
+	if producer.count != 10 || w.Dropped() != 0 {
+		t.Errorf("produced %d, dropped %d, want 10 produced", producer.count, w.Dropped())
+	}
+}
+
+func TestSyslogWriterRedial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	w, err := NewSyslogWriter("tcp://"+addr, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.redialInterval = 10 * time.Millisecond
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the daemon goes away, the writes fail once the peer resets the connection
+	ln.Close()
+	conn.Close()
+	failed := false
+	for i := 0; i < 10 && !failed; i++ {
+		_, err = w.Write([]byte("lost"))
+		failed = err != nil
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !failed {
+		t.Fatal("writes to a closed connection didn't fail")
+	}
+
+	// and comes back on the same address
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	time.Sleep(20 * time.Millisecond)
+	if _, err := w.Write([]byte("back")); err != nil {
+		t.Fatalf("w.Write = %v after syslog came back", err)
+	}
+	conn, err = ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.HasSuffix(line, ": back\n") {
+		t.Errorf("received %q, %v", line, err)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("closed")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("w.Write = %v after Close, want net.ErrClosed", err)
+	}
+}
@@ -0,0 +1,169 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caser789/logger/internal/writer"
+	"go.uber.org/zap/zapcore"
+)
+
+// journaldSocket is replaced by the tests with a fake journald.
+var journaldSocket = writer.JournaldSocket
+
+var journaldFailOnce sync.Once
+
+// journaldFieldPrefix prefixes the fields mapped to a journald variable in journaldReservedKeys.
+const journaldFieldPrefix = "FIELD_"
+
+// journaldReservedKeys are the variables set by journaldCore, or with a meaning to journald, e.g. SYSLOG_PID.
+var journaldReservedKeys = map[string]bool{
+	"MESSAGE": true, "MESSAGE_ID": true, "PRIORITY": true, "TRACE_ID": true, "ERRNO": true,
+	"CODE_FILE": true, "CODE_LINE": true, "CODE_FUNC": true, "DOCUMENTATION": true, "TID": true,
+	"SYSLOG_IDENTIFIER": true, "SYSLOG_FACILITY": true, "SYSLOG_PID": true, "SYSLOG_TIMESTAMP": true, "SYSLOG_RAW": true,
+	"INVOCATION_ID": true, "USER_INVOCATION_ID": true,
+}
+
+// journaldSupported - Return an error if journald is not supported on this platform.
+func journaldSupported() error {
+	return nil
+}
+
+// journaldCore sends each entry to journald, with the formatted line as MESSAGE,
+// the level as PRIORITY, the trace id as TRACE_ID and each other field as a journald variable, e.g. USER_ID for user.id,
+// or FIELD_PRIORITY for priority, see journaldKey.
+type journaldCore struct {
+	zapcore.LevelEnabler
+	enc        zapcore.Encoder
+	fields     []zapcore.Field
+	w          *writer.JournaldWriter
+	identifier string
+}
+
+// newJournaldCore - Return the journald core of opt, or nil if journald is not enabled or can't be reached.
+// The failure is reported once on stderr, and the caller falls back to the log file.
+// Its connection is closed with the logger, see registerSink.
+func newJournaldCore(encoder zapcore.Encoder, opt option, lv zapcore.LevelEnabler) zapcore.Core {
+	if !opt.Journald {
+		return nil
+	}
+	w, err := writer.NewJournaldWriter(journaldSocket)
+	if err != nil {
+		journaldFailOnce.Do(func() {
+			printStderr("can't connect to journald %s, write logs into files instead: %v\n", journaldSocket, err)
+		})
+		return nil
+	}
+	registerSink(opt.Filename, w)
+	return &journaldCore{
+		LevelEnabler: lv,
+		enc:          encoder,
+		w:            w,
+		identifier:   strings.TrimSuffix(filepath.Base(opt.Filename), filepath.Ext(opt.Filename)),
+	}
+}
+
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &journaldCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          enc,
+		fields:       append(c.fields[:len(c.fields):len(c.fields)], fields...),
+		w:            c.w,
+		identifier:   c.identifier,
+	}
+}
+
+func (c *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	m := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(m)
+	}
+	for _, f := range fields {
+		f.AddTo(m)
+	}
+	vars := make(map[string]string, len(m.Fields)+4)
+	for k, v := range m.Fields {
+		if k == TraceKey {
+			vars["TRACE_ID"] = journaldValue(v)
+		} else if key := journaldKey(k); key != "" {
+			vars[key] = journaldValue(v)
+		}
+	}
+	vars["MESSAGE"] = strings.TrimSuffix(buf.String(), "\n")
+	vars["SYSLOG_IDENTIFIER"] = c.identifier
+	if ent.Caller.Defined {
+		vars["CODE_FILE"] = ent.Caller.File
+		vars["CODE_LINE"] = strconv.Itoa(ent.Caller.Line)
+		vars["CODE_FUNC"] = ent.Caller.Function
+	}
+	return c.w.WriteEntry(syslogSeverity(ent.Level), vars)
+}
+
+func (c *journaldCore) Sync() error {
+	return nil
+}
+
+// journaldKey - Return the journald variable name of the field key, in uppercase with the characters
+// other than letters and digits replaced by '_', without the leading '_' reserved to journald,
+// prefixed by journaldFieldPrefix if in journaldReservedKeys, and at most 64 characters.
+// Return "" if nothing is left or it starts with a digit, which journald rejects.
+func journaldKey(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else if b.Len() > 0 {
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return ""
+	}
+	if journaldReservedKeys[name] {
+		name = journaldFieldPrefix + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// journaldValue - Return v as a string, in JSON for the objects and arrays.
+func journaldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool, int64, int32, int16, int8, uint64, uint32, uint16, uint8, uintptr, float64, float32,
+		complex128, complex64, time.Duration, time.Time:
+		return fmt.Sprint(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,163 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caser789/logger/internal/writer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeJournald listens on a journald socket in a temporary directory, and returns the function reading the next entry.
+func fakeJournald(t *testing.T) func() map[string]string {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	old := journaldSocket
+	journaldSocket = path
+	t.Cleanup(func() { journaldSocket = old })
+
+	return func() map[string]string {
+		buf := make([]byte, 65536)
+		assert.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, err := conn.Read(buf)
+		assert.Nil(t, err)
+		vars, err := parseJournaldEntry(buf[:n])
+		assert.Nil(t, err)
+		return vars
+	}
+}
+
+// parseJournaldEntry parses a datagram of the native protocol of journald.
+func parseJournaldEntry(data []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+	for len(data) > 0 {
+		eol := bytes.IndexByte(data, '\n')
+		if eol < 0 {
+			return nil, errors.New("missing new line")
+		}
+		line := data[:eol]
+		data = data[eol+1:]
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			vars[string(line[:eq])] = string(line[eq+1:])
+			continue
+		}
+		if len(data) < 8 {
+			return nil, errors.New("missing length")
+		}
+		size := binary.LittleEndian.Uint64(data)
+		data = data[8:]
+		if uint64(len(data)) < size+1 {
+			return nil, errors.New("short value")
+		}
+		vars[string(line)] = string(data[:size])
+		data = data[size+1:]
+	}
+	return vars, nil
+}
+
+func TestJournald(t *testing.T) {
+	read := fakeJournald(t)
+
+	config := &Config{Path: t.TempDir(), UseJournald: true}
+	assert.Nil(t, validateConfig(config))
+	l := newLogger(getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= DebugLvl
+	}))
+
+	tests := []struct {
+		lvl      LogLevel
+		priority string
+	}{
+		{DebugLvl, "7"},
+		{InfoLvl, "6"},
+		{WarnLvl, "4"},
+		{ErrorLvl, "3"},
+		{DPanicLvl, "2"},
+	}
+	for _, tt := range tests {
+		l.Check(tt.lvl, "to journald").Write()
+		vars := read()
+		assert.Equal(t, tt.priority, vars["PRIORITY"], tt.lvl)
+		assert.Contains(t, vars["MESSAGE"], "|"+tt.lvl.String()+"|")
+		assert.Contains(t, vars["MESSAGE"], "|to journald")
+	}
+
+	l.With(zap.String("user-id", "u1")).Info("fields", zap.Int("count", 3),
+		zap.String("note", "two\nlines"), zap.Any("_obj", map[string]int{"a": 1}),
+		zap.String("priority", "high"), zap.String("message", "user message"), zap.Int("1st", 1))
+	vars := read()
+	assert.Equal(t, "u1", vars["USER_ID"])
+	assert.Equal(t, "3", vars["COUNT"])
+	assert.Equal(t, "two\nlines", vars["NOTE"])
+	assert.Equal(t, `{"a":1}`, vars["OBJ"])
+	assert.Equal(t, "6", vars["PRIORITY"])
+	assert.Equal(t, "high", vars["FIELD_PRIORITY"])
+	assert.Equal(t, "user message", vars["FIELD_MESSAGE"])
+	assert.Contains(t, vars["MESSAGE"], "|fields|")
+	assert.NotContains(t, vars, "1ST")
+	assert.Equal(t, "-", vars["TRACE_ID"])
+	assert.Equal(t, "server", vars["SYSLOG_IDENTIFIER"])
+	assert.Contains(t, vars["CODE_FILE"], "journald_linux_test.go")
+
+	_, err := os.Stat(filepath.Join(config.Path, "server.log"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestJournaldClosed(t *testing.T) {
+	fakeJournald(t)
+
+	config := &Config{Path: t.TempDir(), UseJournald: true}
+	opt := getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})
+	newLogger(opt)
+	sinks := sinksOf(opt.Filename)
+	assert.Len(t, sinks, 1)
+	w, ok := sinks[0].(*writer.JournaldWriter)
+	assert.True(t, ok)
+
+	assert.Nil(t, closeSinks(sinks))
+	assert.Empty(t, sinksOf(opt.Filename))
+	assert.NotNil(t, w.WriteEntry(6, map[string]string{"MESSAGE": "closed"}))
+}
+
+func TestJournaldFallback(t *testing.T) {
+	old := journaldSocket
+	journaldSocket = filepath.Join(t.TempDir(), "missing")
+	defer func() { journaldSocket = old }()
+
+	config := &Config{Path: t.TempDir(), UseJournald: true}
+	l := newLogger(getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	}))
+	l.Info("to file")
+	assert.Nil(t, l.Sync())
+
+	data, err := os.ReadFile(filepath.Join(config.Path, "server.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "to file")
+}
+
+func TestJournaldKey(t *testing.T) {
+	assert.Equal(t, "USER_ID", journaldKey("user.id"))
+	assert.Equal(t, "HTTP_STATUS", journaldKey("http.status"))
+	assert.Equal(t, "PRIVATE", journaldKey("__private"))
+	assert.Equal(t, "", journaldKey("_"))
+	assert.Len(t, journaldKey(string(make([]byte, 100))+"x"), 1)
+	assert.Equal(t, "FIELD_PRIORITY", journaldKey("priority"))
+	assert.Equal(t, "FIELD_CODE_LINE", journaldKey("code.line"))
+	assert.Equal(t, "FIELD_SYSLOG_IDENTIFIER", journaldKey("syslog_identifier"))
+	assert.Equal(t, "", journaldKey("2fa"))
+	assert.Equal(t, "", journaldKey("_9"))
+	assert.Equal(t, "USER2", journaldKey("user2"))
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package log
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// journaldSupported - Return an error if journald is not supported on this platform.
+func journaldSupported() error {
+	return errors.New("journald is only supported on linux")
+}
+
+// newJournaldCore - Return nil, journald is not supported on this platform.
+func newJournaldCore(zapcore.Encoder, option, zapcore.LevelEnabler) zapcore.Core {
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournaldUnsupported(t *testing.T) {
+	err := validateConfig(&Config{UseJournald: true})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "journald is only supported on linux")
+}
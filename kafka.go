@@ -0,0 +1,69 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/caser789/logger/internal/writer"
+)
+
+// KafkaProducer sends messages to a Kafka topic. This package doesn't depend on any Kafka client,
+// implement it with the client of your choice and register it with RegisterKafkaProducer.
+type KafkaProducer = writer.Producer
+
+// KafkaProducerFactory creates a KafkaProducer connected to the brokers.
+type KafkaProducerFactory func(brokers []string) (KafkaProducer, error)
+
+var (
+	kafkaProducerFactory KafkaProducerFactory
+	kafkaMutex           sync.Mutex
+	kafkaFailOnce        sync.Once
+)
+
+// RegisterKafkaProducer - Register the factory used to create the producers when Config.KafkaBrokers is set.
+// Should be called before logger initialization.
+func RegisterKafkaProducer(factory KafkaProducerFactory) {
+	kafkaMutex.Lock()
+	defer kafkaMutex.Unlock()
+	kafkaProducerFactory = factory
+}
+
+// newKafkaWriter - Return the Kafka writer of opt, or nil if Kafka is not enabled or the producer can't be created.
+// The failure is reported once on stderr, and the caller falls back to the log file.
+func newKafkaWriter(opt option) io.Writer {
+	if len(opt.KafkaBrokers) == 0 || opt.KafkaTopic == "" {
+		return nil
+	}
+	kafkaMutex.Lock()
+	factory := kafkaProducerFactory
+	kafkaMutex.Unlock()
+
+	var producer KafkaProducer
+	err := fmt.Errorf("no producer registered")
+	if factory != nil {
+		producer, err = factory(opt.KafkaBrokers)
+	}
+	if err != nil {
+		kafkaFailOnce.Do(func() {
+			printStderr("can't create kafka producer for %v, write logs into files instead: %v\n",
+				opt.KafkaBrokers, err)
+		})
+		return nil
+	}
+	w := writer.NewKafkaWriterPolicy(producer, opt.KafkaTopic, 0, queueFullPolicy(opt.QueuePolicy))
+	registerSink(opt.Filename, w)
+	return w
+}
+
+// queueFullPolicy - Return the writer policy of p, dropping new logs by default.
+func queueFullPolicy(p QueueFullPolicy) writer.QueueFullPolicy {
+	switch p {
+	case QueueFullBlock:
+		return writer.QueueBlock
+	case QueueFullDropOldest:
+		return writer.QueueDropOldest
+	default:
+		return writer.QueueDrop
+	}
+}
@@ -0,0 +1,157 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockKafkaProducer struct {
+	mu       sync.Mutex
+	brokers  []string
+	messages map[string][]string
+	closed   bool
+}
+
+func (p *mockKafkaProducer) Produce(topic string, value []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages[topic] = append(p.messages[topic], string(value))
+	return nil
+}
+
+func (p *mockKafkaProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func TestKafkaSink(t *testing.T) {
+	producer := &mockKafkaProducer{messages: map[string][]string{}}
+	RegisterKafkaProducer(func(brokers []string) (KafkaProducer, error) {
+		producer.brokers = brokers
+		return producer, nil
+	})
+	defer RegisterKafkaProducer(nil)
+
+	config := &Config{Path: t.TempDir(), KafkaBrokers: []string{"127.0.0.1:9092"}, KafkaTopic: "logs"}
+	l := newLogger(getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	}))
+	l.Info("first")
+	l.Info("second")
+	l.Debug("filtered")
+	assert.Nil(t, l.Sync())
+
+	producer.mu.Lock()
+	defer producer.mu.Unlock()
+	assert.Equal(t, []string{"127.0.0.1:9092"}, producer.brokers)
+	messages := producer.messages["logs"]
+	assert.Equal(t, 2, len(messages))
+	assert.Contains(t, messages[0], "|info|")
+	assert.Contains(t, messages[0], "|first\n")
+	assert.Contains(t, messages[1], "|second\n")
+
+	_, err := os.Stat(filepath.Join(config.Path, "server.log"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestKafkaSinkFallback(t *testing.T) {
+	RegisterKafkaProducer(func(brokers []string) (KafkaProducer, error) {
+		return nil, errors.New("no broker")
+	})
+	defer RegisterKafkaProducer(nil)
+
+	config := &Config{Path: t.TempDir(), KafkaBrokers: []string{"127.0.0.1:9092"}, KafkaTopic: "logs"}
+	l := newLogger(getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	}))
+	l.Info("to file")
+	assert.Nil(t, l.Sync())
+
+	data, err := os.ReadFile(filepath.Join(config.Path, "server.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "to file")
+}
+
+func TestKafkaSinkQueueFullPolicy(t *testing.T) {
+	producer := &mockKafkaProducer{messages: map[string][]string{}}
+	RegisterKafkaProducer(func(brokers []string) (KafkaProducer, error) {
+		return producer, nil
+	})
+	defer RegisterKafkaProducer(nil)
+
+	config := &Config{Path: t.TempDir(), KafkaBrokers: []string{"127.0.0.1:9092"}, KafkaTopic: "logs",
+		QueueFullPolicy: QueueFullBlock}
+	opt := getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})
+	l := newLogger(opt)
+	for i := 0; i < 3; i++ {
+		l.Info("queued")
+	}
+	assert.Nil(t, l.Sync())
+
+	stats, ok := AsyncQueueStats()[opt.Filename]
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), stats.Enqueued)
+	assert.Equal(t, uint64(0), stats.Dropped)
+}
+
+func TestKafkaSinkClosedByReinit(t *testing.T) {
+	oldLogger, oldSysLogger, oldTracingLogger := logger.Load(), sysLogger.Load(), tracingLogger.Load()
+	oldAccessLogger, oldAuditLogger := accessLogger.Load(), auditLogger.Load()
+	defer func() {
+		logger.Store(oldLogger)
+		sysLogger.Store(oldSysLogger)
+		tracingLogger.Store(oldTracingLogger)
+		accessLogger.Store(oldAccessLogger)
+		auditLogger.Store(oldAuditLogger)
+		initConfigMutex.Lock()
+		initConfig = nil
+		initConfigMutex.Unlock()
+	}()
+
+	var mu sync.Mutex
+	var producers []*mockKafkaProducer
+	RegisterKafkaProducer(func(brokers []string) (KafkaProducer, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		p := &mockKafkaProducer{messages: map[string][]string{}}
+		producers = append(producers, p)
+		return p, nil
+	})
+	defer RegisterKafkaProducer(nil)
+
+	dir := t.TempDir()
+	ReinitLogger(&Config{Path: dir, KafkaBrokers: []string{"127.0.0.1:9092"}, KafkaTopic: "logs"})
+	GetLogger().Info("to kafka")
+	mu.Lock()
+	assert.NotEmpty(t, producers)
+	first := len(producers)
+	mu.Unlock()
+	assert.Contains(t, AsyncQueueStats(), filepath.Join(dir, DefaultLogFileName+".log"))
+
+	ReinitLogger(&Config{Path: dir})
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, first, len(producers))
+	// the queued logs are sent before closing
+	sent := 0
+	for _, p := range producers {
+		p.mu.Lock()
+		assert.True(t, p.closed)
+		for _, m := range p.messages["logs"] {
+			sent += strings.Count(m, "to kafka")
+		}
+		p.mu.Unlock()
+	}
+	assert.Equal(t, 1, sent)
+	assert.NotContains(t, AsyncQueueStats(), filepath.Join(dir, DefaultLogFileName+".log"))
+}
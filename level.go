@@ -0,0 +1,241 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/caser789/logger/internal/utils/env"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	userLevel    = &LevelController{}
+	sysLevel     = &LevelController{}
+	tracingLevel = &LevelController{}
+
+	// minLevelInLive is set by Config.MinLevelInLive.
+	minLevelInLive atomic.Int32
+)
+
+// LevelController - The dynamic level of one logger.
+// A level set with a duration is reset to the level set before after the duration, see SetLevel.
+type LevelController struct {
+	level atomic.Int32
+
+	mutex        sync.Mutex
+	resetVer     int64
+	resetPending bool
+	resetTo      LogLevel
+
+	callbacksMutex sync.Mutex
+	callbacks      []func(old, new LogLevel)
+}
+
+// Level - Return the current level.
+func (c *LevelController) Level() LogLevel {
+	return zapcore.Level(int8(c.level.Load()))
+}
+
+// Enabled - Return true if lvl is at or above the current level.
+func (c *LevelController) Enabled(lvl LogLevel) bool {
+	return lvl >= c.Level()
+}
+
+// SetLevel - Set the level. Time duration only works when setting the level to debug,
+// the level is then reset to the level set before this call after the duration.
+// When timed calls overlap, the level is reset to the level set before the first of them.
+// On live environment, the level can't go below Config.MinLevelInLive (info by default) without a duration.
+func (c *LevelController) SetLevel(level LogLevel, duration time.Duration) {
+	old, level := c.setLevel(level, duration)
+	c.notify(old, level)
+}
+
+// setLevel - Return the level before and after the call.
+func (c *LevelController) setLevel(level LogLevel, duration time.Duration) (LogLevel, LogLevel) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if level < zap.InfoLevel && duration > 0 {
+		if duration > maxResetLvlDur {
+			duration = maxResetLvlDur
+		}
+
+		c.resetVer++
+		if c.resetVer > maxResetVer {
+			c.resetVer = c.resetVer % maxResetVer
+		}
+		if !c.resetPending {
+			c.resetTo = c.Level()
+			c.resetPending = true
+		}
+
+		ver := c.resetVer
+		time.AfterFunc(duration, func() {
+			c.resetLevel(ver)
+		})
+	} else {
+		if floor := zapcore.Level(int8(minLevelInLive.Load())); env.IsLive() && level < floor {
+			level = floor
+		}
+		c.resetPending = false
+	}
+
+	return zapcore.Level(int8(c.level.Swap(int32(level)))), level
+}
+
+func (c *LevelController) resetLevel(ver int64) {
+	c.mutex.Lock()
+	if !c.resetPending || c.resetVer != ver {
+		c.mutex.Unlock()
+		return
+	}
+	c.resetPending = false
+	old, level := c.Level(), c.resetTo
+	if old < InfoLvl {
+		c.level.Store(int32(level))
+	}
+	c.mutex.Unlock()
+
+	if old < InfoLvl {
+		c.notify(old, level)
+	}
+}
+
+// OnChange - Register a callback invoked with the old and new level whenever the level changes,
+// by SetLevel or by a timed reset. Callbacks are invoked synchronously, in registration order.
+func (c *LevelController) OnChange(fn func(old, new LogLevel)) {
+	c.callbacksMutex.Lock()
+	defer c.callbacksMutex.Unlock()
+	c.callbacks = append(c.callbacks, fn)
+}
+
+func (c *LevelController) notify(old, level LogLevel) {
+	if old == level {
+		return
+	}
+	c.callbacksMutex.Lock()
+	callbacks := c.callbacks
+	c.callbacksMutex.Unlock()
+	for _, fn := range callbacks {
+		fn(old, level)
+	}
+}
+
+// OnLevelChange - Register a callback invoked with the old and new level whenever the level of the user logger
+// (i.e. GetLevel) changes, by SetLevel, SetUserLevel or a timed reset, e.g. to mirror it into a metrics gauge.
+// Callbacks are invoked synchronously, in registration order.
+func OnLevelChange(fn func(old, new LogLevel)) {
+	userLevel.OnChange(fn)
+}
+
+// SetUserLevel - Dynamically set the level of the user logger only, with the same timed reset as SetLevel.
+func SetUserLevel(level LogLevel, duration time.Duration) {
+	userLevel.SetLevel(level, duration)
+}
+
+// SetSysLevel - Dynamically set the level of the system logger only, with the same timed reset as SetLevel.
+func SetSysLevel(level LogLevel, duration time.Duration) {
+	sysLevel.SetLevel(level, duration)
+}
+
+// SetTracingLevel - Dynamically set the level of the tracing logger only, with the same timed reset as SetLevel.
+func SetTracingLevel(level LogLevel, duration time.Duration) {
+	tracingLevel.SetLevel(level, duration)
+}
+
+var (
+	rolloutDraw     float64
+	rolloutDrawOnce sync.Once
+	// newRolloutDraw is replaced by the tests to simulate many processes.
+	newRolloutDraw = func() float64 {
+		return rand.New(rand.NewSource(time.Now().UnixNano() ^ int64(os.Getpid())<<32)).Float64()
+	}
+)
+
+// SetLevelSampled - Like SetLevel, but only applied by a fraction (between 0 and 1) of the processes,
+// e.g. to set the debug level on 10% of the replicas of a service when called on all of them.
+// Each process draws once whether it is in the fraction, so the same replicas opt in on every call
+// with the same fraction. Return true if the level is set by this process.
+func SetLevelSampled(level LogLevel, fraction float64, duration time.Duration) bool {
+	if !inRollout(fraction) {
+		return false
+	}
+	SetLevel(level, duration)
+	return true
+}
+
+func inRollout(fraction float64) bool {
+	rolloutDrawOnce.Do(func() {
+		rolloutDraw = newRolloutDraw()
+	})
+	return rolloutDraw < fraction
+}
+
+// GetSysLevel - Return the level of the system logger.
+func GetSysLevel() LogLevel {
+	return sysLevel.Level()
+}
+
+// GetTracingLevel - Return the level of the tracing logger.
+func GetTracingLevel() LogLevel {
+	return tracingLevel.Level()
+}
+
+type levelPayload struct {
+	Level    *zapcore.Level `json:"level"`
+	Duration string         `json:"duration,omitempty"`
+}
+
+type levelError struct {
+	Error string `json:"error"`
+}
+
+// LevelHandler - Return a http handler to query and change the log level at runtime, like zap's AtomicLevel.
+// GET returns the current level, e.g. {"level":"info"}.
+// PUT sets the level with SetLevel, e.g. {"level":"debug","duration":"30s"}, the duration being optional.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+
+		switch r.Method {
+		case http.MethodGet:
+			current := GetLevel()
+			_ = enc.Encode(levelPayload{Level: &current})
+		case http.MethodPut:
+			var req levelPayload
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = enc.Encode(levelError{Error: fmt.Sprintf("request body must be well-formed JSON: %v", err)})
+				return
+			}
+			if req.Level == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = enc.Encode(levelError{Error: "must specify a logging level"})
+				return
+			}
+			var duration time.Duration
+			if req.Duration != "" {
+				d, err := time.ParseDuration(req.Duration)
+				if err != nil || d < 0 {
+					w.WriteHeader(http.StatusBadRequest)
+					_ = enc.Encode(levelError{Error: fmt.Sprintf("invalid duration %q", req.Duration)})
+					return
+				}
+				duration = d
+			}
+			SetLevel(*req.Level, duration)
+			_ = enc.Encode(req)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_ = enc.Encode(levelError{Error: "only GET and PUT are supported"})
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerLoggerLevel(t *testing.T) {
+	defer SetLevel(GetLevel(), 0)
+	SetLevel(InfoLvl, 0)
+	SetUserLevel(DebugLvl, 0)
+	assert.Equal(t, DebugLvl, GetLevel())
+	assert.Equal(t, InfoLvl, GetSysLevel())
+	assert.Equal(t, InfoLvl, GetTracingLevel())
+
+	l, read := newTestLogger(t, &Config{})
+	l.Debug("user debug")
+	assert.Contains(t, read(), "user debug")
+
+	config := &Config{Path: t.TempDir()}
+	initSystemLogger(config)
+	sysLogger.Load().Debug("sys debug")
+	sysLogger.Load().Info("sys info")
+	_ = sysLogger.Load().Sync()
+
+	data, err := os.ReadFile(filepath.Join(config.Path, SysLogFileName+".log"))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "sys debug")
+	assert.Contains(t, string(data), "sys info")
+}
+
+func TestSetLevelSetsAllLoggers(t *testing.T) {
+	defer SetLevel(GetLevel(), 0)
+	SetSysLevel(DebugLvl, 0)
+	SetLevel(WarnLvl, 0)
+	assert.Equal(t, WarnLvl, GetLevel())
+	assert.Equal(t, WarnLvl, GetSysLevel())
+	assert.Equal(t, WarnLvl, GetTracingLevel())
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer SetLevel(GetLevel(), 0)
+	server := httptest.NewServer(LevelHandler())
+	defer server.Close()
+
+	put := func(body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, put(`{"level":"error"}`).StatusCode)
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, `{"level":"error"}`, strings.TrimSpace(string(body)))
+	assert.Equal(t, ErrorLvl, GetSysLevel())
+
+	assert.Equal(t, http.StatusBadRequest, put(`{"level":"verbose"}`).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, put(`{}`).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, put(`{"level":"debug","duration":"soon"}`).StatusCode)
+	assert.Equal(t, ErrorLvl, GetLevel())
+}
+
+func TestSetLevelResetsToPreviousLevel(t *testing.T) {
+	os.Setenv("ENV", "live")
+	defer os.Unsetenv("ENV")
+	c := &LevelController{}
+
+	c.SetLevel(WarnLvl, 0)
+	c.SetLevel(DebugLvl, 20*time.Millisecond)
+	assert.Equal(t, DebugLvl, c.Level())
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, WarnLvl, c.Level())
+
+	// overlapping timers reset to the level before the first one
+	c.SetLevel(ErrorLvl, 0)
+	c.SetLevel(DebugLvl, 20*time.Millisecond)
+	c.SetLevel(DebugLvl, 40*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, DebugLvl, c.Level())
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, ErrorLvl, c.Level())
+}
+
+func TestMinLevelInLive(t *testing.T) {
+	os.Setenv("ENV", "live")
+	defer os.Unsetenv("ENV")
+	defer minLevelInLive.Store(minLevelInLive.Load())
+	minLevelInLive.Store(int32(InfoLvl))
+	c := &LevelController{}
+
+	c.SetLevel(WarnLvl, 0)
+	c.SetLevel(DebugLvl, 0)
+	assert.Equal(t, InfoLvl, c.Level())
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, InfoLvl, c.Level())
+
+	// debug with a duration still goes below the floor until it is reset
+	c.SetLevel(DebugLvl, 20*time.Millisecond)
+	assert.Equal(t, DebugLvl, c.Level())
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, InfoLvl, c.Level())
+
+	minLevelInLive.Store(int32(WarnLvl))
+	c.SetLevel(InfoLvl, 0)
+	assert.Equal(t, WarnLvl, c.Level())
+	c.SetLevel(ErrorLvl, 0)
+	assert.Equal(t, ErrorLvl, c.Level())
+
+	// the floor only applies to live
+	os.Setenv("ENV", "dev")
+	c.SetLevel(InfoLvl, 0)
+	assert.Equal(t, InfoLvl, c.Level())
+}
+
+func TestSetLevelResetsInDevEnv(t *testing.T) {
+	os.Setenv("ENV", "dev")
+	defer os.Unsetenv("ENV")
+	c := &LevelController{}
+
+	c.SetLevel(InfoLvl, 0)
+	c.SetLevel(DebugLvl, 100*time.Millisecond)
+	assert.Equal(t, DebugLvl, c.Level())
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, InfoLvl, c.Level())
+
+	// without a duration, debug is kept outside live
+	c.SetLevel(DebugLvl, 0)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, DebugLvl, c.Level())
+}
+
+func TestOnLevelChange(t *testing.T) {
+	c := &LevelController{}
+	c.SetLevel(InfoLvl, 0)
+
+	var mutex sync.Mutex
+	var changes [][2]LogLevel
+	var order []int
+	c.OnChange(func(old, new LogLevel) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		changes = append(changes, [2]LogLevel{old, new})
+		order = append(order, 1)
+	})
+	c.OnChange(func(old, new LogLevel) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		order = append(order, 2)
+	})
+
+	c.SetLevel(InfoLvl, 0)
+	c.SetLevel(DebugLvl, 20*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	assert.Equal(t, [][2]LogLevel{{InfoLvl, DebugLvl}, {DebugLvl, InfoLvl}}, changes)
+	assert.Equal(t, []int{1, 2, 1, 2}, order)
+}
+
+func TestSetLevelSampled(t *testing.T) {
+	defer SetLevel(GetLevel(), 0)
+	defer func(draw func() float64) {
+		newRolloutDraw = draw
+		rolloutDrawOnce = sync.Once{}
+	}(newRolloutDraw)
+
+	// each iteration simulates a process drawing once
+	const processes = 10000
+	optedIn := 0
+	for i := 0; i < processes; i++ {
+		rolloutDrawOnce = sync.Once{}
+		if inRollout(0.1) {
+			optedIn++
+		}
+		// the draw is kept by the process
+		assert.Equal(t, inRollout(0.1), inRollout(0.1))
+	}
+	assert.InDelta(t, 0.1, float64(optedIn)/processes, 0.02)
+
+	rolloutDrawOnce = sync.Once{}
+	newRolloutDraw = func() float64 { return 0.05 }
+	SetLevel(InfoLvl, 0)
+	assert.True(t, SetLevelSampled(DebugLvl, 0.1, 50*time.Millisecond))
+	assert.Equal(t, DebugLvl, GetLevel())
+	assert.Equal(t, DebugLvl, GetSysLevel())
+	// the timed reset applies to the opted in processes
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, InfoLvl, GetLevel())
+
+	assert.False(t, SetLevelSampled(DebugLvl, 0.05, 0))
+	assert.False(t, SetLevelSampled(DebugLvl, 0, 0))
+	assert.Equal(t, InfoLvl, GetLevel())
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// lineWriterSkip skips the log method and the Write or Close of lineWriter,
+// so the caller of the logs is the code writing them.
+var lineWriterSkip = zap.AddCallerSkip(2)
+
+// Writer - Return a writer logging each line written into it as one log of the logger of ctx (see TraceLogger)
+// at level, e.g. to pipe the output of a subprocess into the logs. A line is logged once its newline is written,
+// Close logs the remaining partial line.
+func Writer(ctx context.Context, level LogLevel) io.WriteCloser {
+	return &lineWriter{logger: TraceLogger(ctx).WithOptions(lineWriterSkip), level: level}
+}
+
+type lineWriter struct {
+	logger *zap.Logger
+	level  LogLevel
+
+	mutex sync.Mutex
+	buf   []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	data := p
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if len(w.buf) > 0 {
+			w.buf = append(w.buf, data[:i]...)
+			w.log(w.buf)
+			w.buf = w.buf[:0]
+		} else {
+			w.log(data[:i])
+		}
+		data = data[i+1:]
+	}
+	w.buf = append(w.buf, data...)
+	return len(p), nil
+}
+
+// Close - Log the partial line written since the last newline, if any.
+func (w *lineWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.buf) > 0 {
+		w.log(w.buf)
+		w.buf = w.buf[:0]
+	}
+	return nil
+}
+
+func (w *lineWriter) log(line []byte) {
+	if ce := w.logger.Check(w.level, string(bytes.TrimSuffix(line, []byte("\r")))); ce != nil {
+		ce.Write()
+	}
+}
@@ -0,0 +1,36 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/caser789/logger/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestWriter(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	sc := trace.NewSpanContextGenerator("").NewSpanContext()
+	ctx := WithLogger(WithSpanContext(context.Background(), sc), l.With(zap.String(TraceKey, sc.String())))
+
+	w := Writer(ctx, WarnLvl)
+	_, _ = w.Write([]byte("first "))
+	_, _ = w.Write([]byte("line\nsecond line\nthi"))
+	assert.Len(t, strings.Split(strings.TrimSpace(read()), "\n"), 2)
+	_, _ = fmt.Fprint(w, "rd line\r\npartial")
+	assert.Nil(t, w.Close())
+
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Len(t, lines, 4)
+	for i, msg := range []string{"first line", "second line", "third line", "partial"} {
+		assert.Contains(t, lines[i], "|warn|")
+		assert.True(t, strings.HasSuffix(lines[i], "|"+sc.String()+"|"+msg), lines[i])
+	}
+
+	// nothing left to flush
+	assert.Nil(t, w.Close())
+	assert.Len(t, strings.Split(strings.TrimSpace(read()), "\n"), 4)
+}
@@ -2,67 +2,100 @@ package log
 
 import (
 	"context"
+
+	"github.com/caser789/logger/internal/trace"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
 
-const TraceKey = "@jiao_trace_id"
+const (
+	TraceKey = "@jiao_trace_id"
+	SeqKey   = "seq"
+	// SampledKey and SampleReasonKey are the keys of the fields attached by Config.TracingLogSampling.
+	SampledKey      = "sampled"
+	SampleReasonKey = "sample_reason"
+	// ReqTypeKey and CriticalKey are the keys of the fields attached by Config.TracingSpanFields, with SampledKey.
+	ReqTypeKey  = "req_type"
+	CriticalKey = "critical"
+	// DroppedKey is the key of the number of logs suppressed by LogEvery.
+	DroppedKey = "dropped"
+)
+
+var (
+	// tracingLogSampling is set by Config.TracingLogSampling.
+	tracingLogSampling atomic.Bool
+	// alwaysRecordTracing is set by Config.AlwaysRecordTracing.
+	alwaysRecordTracing atomic.Bool
+	// tracingSpanFields is set by Config.TracingSpanFields.
+	tracingSpanFields atomic.Bool
+
+	nopLogger = zap.NewNop()
+)
 
 // Log Interfaces
 
 func Debug(ctx context.Context, msg string, fields ...zap.Field) {
-	GetTraceLogFromCtx(ctx).Debug(msg, fields...)
+	getCtxLogger(ctx).Debug(msg, fields...)
 }
 
-func Debugf(ctx context.Context, args ...interface{}) {
-	GetTraceLogFromCtx(ctx).Sugar().Debug(args)
+func Debugf(ctx context.Context, format string, args ...interface{}) {
+	getCtxLogger(ctx).Sugar().Debugf(format, args...)
 }
 
 func Info(ctx context.Context, msg string, fields ...zap.Field) {
-	GetTraceLogFromCtx(ctx).Info(msg, fields...)
+	getCtxLogger(ctx).Info(msg, fields...)
 }
 
-func Infof(ctx context.Context, args ...interface{}) {
-	GetTraceLogFromCtx(ctx).Sugar().Info(args)
+func Infof(ctx context.Context, format string, args ...interface{}) {
+	getCtxLogger(ctx).Sugar().Infof(format, args...)
 }
 
 func Warn(ctx context.Context, msg string, fields ...zap.Field) {
-	GetTraceLogFromCtx(ctx).Warn(msg, fields...)
+	getCtxLogger(ctx).Warn(msg, fields...)
 }
 
-func Warnf(ctx context.Context, args ...interface{}) {
-	GetTraceLogFromCtx(ctx).Sugar().Warn(args)
+func Warnf(ctx context.Context, format string, args ...interface{}) {
+	getCtxLogger(ctx).Sugar().Warnf(format, args...)
 }
 
 func Error(ctx context.Context, msg string, fields ...zap.Field) {
-	GetTraceLogFromCtx(ctx).Error(msg, fields...)
+	getCtxLogger(ctx).Error(msg, fields...)
 }
 
-func Errorf(ctx context.Context, args ...interface{}) {
-	GetTraceLogFromCtx(ctx).Sugar().Error(args)
+func Errorf(ctx context.Context, format string, args ...interface{}) {
+	getCtxLogger(ctx).Sugar().Errorf(format, args...)
 }
 
 func DPanic(ctx context.Context, msg string, fields ...zap.Field) {
-	GetTraceLogFromCtx(ctx).DPanic(msg, fields...)
+	getCtxLogger(ctx).DPanic(msg, fields...)
 }
 
-func DPanicf(ctx context.Context, args ...interface{}) {
-	GetTraceLogFromCtx(ctx).Sugar().DPanic(args)
+func DPanicf(ctx context.Context, format string, args ...interface{}) {
+	getCtxLogger(ctx).Sugar().DPanicf(format, args...)
 }
 
 func Panic(ctx context.Context, msg string, fields ...zap.Field) {
-	GetTraceLogFromCtx(ctx).Panic(msg, fields...)
+	getCtxLogger(ctx).Panic(msg, fields...)
 }
 
-func Panicf(ctx context.Context, args ...interface{}) {
-	GetTraceLogFromCtx(ctx).Sugar().Panic(args)
+func Panicf(ctx context.Context, format string, args ...interface{}) {
+	getCtxLogger(ctx).Sugar().Panicf(format, args...)
 }
 
 func Fatal(ctx context.Context, msg string, fields ...zap.Field) {
-	GetTraceLogFromCtx(ctx).Fatal(msg, fields...)
+	getCtxLogger(ctx).Fatal(msg, fields...)
 }
 
-func Fatalf(ctx context.Context, args ...interface{}) {
-	GetTraceLogFromCtx(ctx).Sugar().Fatal(args)
+func Fatalf(ctx context.Context, format string, args ...interface{}) {
+	getCtxLogger(ctx).Sugar().Fatalf(format, args...)
+}
+
+// LogWithSeq - Log msg in the given level with a caller-provided sequence attached under the "seq" key,
+// e.g. a Lamport clock, so that entries can be ordered by the caller's own scheme.
+func LogWithSeq(ctx context.Context, seq uint64, level LogLevel, msg string, fields ...zap.Field) {
+	if ce := getCtxLogger(ctx).Check(level, msg); ce != nil {
+		ce.Write(append([]zap.Field{zap.Uint64(SeqKey, seq)}, fields...)...)
+	}
 }
 
 // System log interface
@@ -73,8 +106,8 @@ func SysDebug(ctx context.Context, msg string, fields ...zap.Field) {
 }
 
 // SysDebugf - System sugar log in DebugLvl level.
-func SysDebugf(ctx context.Context, args ...interface{}) {
-	getSysLogger(ctx).Sugar().Debug(args)
+func SysDebugf(ctx context.Context, format string, args ...interface{}) {
+	getSysLogger(ctx).Sugar().Debugf(format, args...)
 }
 
 // SysInfo - System log in InfoLvl level.
@@ -83,8 +116,8 @@ func SysInfo(ctx context.Context, msg string, fields ...zap.Field) {
 }
 
 // SysInfof - System sugar log in InfoLvl level.
-func SysInfof(ctx context.Context, args ...interface{}) {
-	getSysLogger(ctx).Sugar().Info(args)
+func SysInfof(ctx context.Context, format string, args ...interface{}) {
+	getSysLogger(ctx).Sugar().Infof(format, args...)
 }
 
 // SysWarn - System log in WarnLvl level.
@@ -93,8 +126,8 @@ func SysWarn(ctx context.Context, msg string, fields ...zap.Field) {
 }
 
 // SysWarnf - System sugar log in WarnLvl level.
-func SysWarnf(ctx context.Context, args ...interface{}) {
-	getSysLogger(ctx).Sugar().Warn(args)
+func SysWarnf(ctx context.Context, format string, args ...interface{}) {
+	getSysLogger(ctx).Sugar().Warnf(format, args...)
 }
 
 // SysError - System log in ErrorLvl level.
@@ -103,8 +136,8 @@ func SysError(ctx context.Context, msg string, fields ...zap.Field) {
 }
 
 // SysErrorf - System sugar log in ErrorLvl level.
-func SysErrorf(ctx context.Context, args ...interface{}) {
-	getSysLogger(ctx).Sugar().Error(args)
+func SysErrorf(ctx context.Context, format string, args ...interface{}) {
+	getSysLogger(ctx).Sugar().Errorf(format, args...)
 }
 
 // SysDPanic - System log in DPanicLvl level.
@@ -113,8 +146,8 @@ func SysDPanic(ctx context.Context, msg string, fields ...zap.Field) {
 }
 
 // SysDPanicf - System sugar log in DPanicLvl level.
-func SysDPanicf(ctx context.Context, args ...interface{}) {
-	getSysLogger(ctx).Sugar().DPanic(args)
+func SysDPanicf(ctx context.Context, format string, args ...interface{}) {
+	getSysLogger(ctx).Sugar().DPanicf(format, args...)
 }
 
 // SysPanic - System log in PanicLvl level.
@@ -123,8 +156,8 @@ func SysPanic(ctx context.Context, msg string, fields ...zap.Field) {
 }
 
 // SysPanicf - System sugar log in PanicLvl level.
-func SysPanicf(ctx context.Context, args ...interface{}) {
-	getSysLogger(ctx).Sugar().Panic(args)
+func SysPanicf(ctx context.Context, format string, args ...interface{}) {
+	getSysLogger(ctx).Sugar().Panicf(format, args...)
 }
 
 // SysFatal - System log in FatalLvl level.
@@ -133,18 +166,46 @@ func SysFatal(ctx context.Context, msg string, fields ...zap.Field) {
 }
 
 // SysFatalf - System sugar log in FatalLvl level.
-func SysFatalf(ctx context.Context, args ...interface{}) {
-	getSysLogger(ctx).Sugar().Fatal(args)
+func SysFatalf(ctx context.Context, format string, args ...interface{}) {
+	getSysLogger(ctx).Sugar().Fatalf(format, args...)
+}
+
+// helperSkip skips the helpers of this file in the caller of the logs, so it is the code calling them.
+var helperSkip = zap.AddCallerSkip(1)
+
+// getCtxLogger - Return the logger of ctx for the helpers of this file, see GetTraceLogFromCtx.
+func getCtxLogger(ctx context.Context) *zap.Logger {
+	return GetTraceLogFromCtx(ctx).WithOptions(helperSkip)
 }
 
 func getSysLogger(ctx context.Context) *zap.Logger {
 	traceID := GetTraceIDFromCtx(ctx)
-	return GetSysLogger().With(zap.String(TraceKey, traceID))
+	return GetSysLogger().WithOptions(helperSkip).With(zap.String(TraceKey, traceID))
 }
 
+// getTracingLogger - Return the tracing logger of ctx, a no-op logger if the trace of ctx is not sampled,
+// unless Config.AlwaysRecordTracing or Config.TracingLogSampling, which audits them, is set.
 func getTracingLogger(ctx context.Context) *zap.Logger {
-	traceID := GetTraceIDFromCtx(ctx)
-	return GetTracingLogger().With(zap.String(TraceKey, traceID))
+	sc := GetSpanContext(ctx)
+	withSampling := tracingLogSampling.Load()
+	if sc != nil && !withSampling && !alwaysRecordTracing.Load() && !trace.IsSpanContextSampled(sc) {
+		return nopLogger
+	}
+	fields := []zap.Field{zap.String(TraceKey, GetTraceIDFromCtx(ctx))}
+	if withSampling {
+		fields = append(fields,
+			zap.Bool(SampledKey, trace.IsSpanContextSampled(sc)),
+			zap.String(SampleReasonKey, trace.GetSampleReason(sc)),
+		)
+	}
+	if tracingSpanFields.Load() && sc != nil {
+		fields = append(fields, zap.String(ReqTypeKey, trace.GetRequestType(sc)))
+		if !withSampling {
+			fields = append(fields, zap.Bool(SampledKey, trace.IsSpanContextSampled(sc)))
+		}
+		fields = append(fields, zap.Bool(CriticalKey, trace.IsSpanContextCritical(sc)))
+	}
+	return GetTracingLogger().WithOptions(helperSkip).With(fields...)
 }
 
 // Tracing Log Interface
@@ -155,14 +216,14 @@ func Tracing(ctx context.Context, msg string, fields ...zap.Field) {
 }
 
 // Tracingf tracing sugar log,info level only
-func Tracingf(ctx context.Context, args ...interface{}) {
-	getTracingLogger(ctx).Sugar().Info(args)
+func Tracingf(ctx context.Context, format string, args ...interface{}) {
+	getTracingLogger(ctx).Sugar().Infof(format, args...)
 }
 
 func TracingDebug(ctx context.Context, msg string, fields ...zap.Field) {
 	getTracingLogger(ctx).Debug(msg, fields...)
 }
 
-func TracingDebugf(ctx context.Context, args ...interface{}) {
-	getTracingLogger(ctx).Sugar().Debug(args)
+func TracingDebugf(ctx context.Context, format string, args ...interface{}) {
+	getTracingLogger(ctx).Sugar().Debugf(format, args...)
 }
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+// maxLogEverySites bounds the call sites tracked by LogEvery, and by LogFirstN.
+const maxLogEverySites = 4096
+
+// callSiteKey is the file and line of a call site of LogEvery or LogFirstN.
+type callSiteKey struct {
+	file string
+	line int
+}
+
+type logEverySite struct {
+	last     time.Time
+	interval time.Duration
+	dropped  uint64
+}
+
+var (
+	logEveryMutex sync.Mutex
+	logEverySites = map[callSiteKey]*logEverySite{}
+	// logEveryNow exists so it can be mocked out by tests.
+	logEveryNow = time.Now
+
+	logFirstNMutex  sync.Mutex
+	logFirstNCounts = map[callSiteKey]*atomic.Uint64{}
+)
+
+// LogEvery - Log msg in the given level at most once per interval for each call site, e.g. for an error
+// logged on every request of a hot path. The first log after the interval carries the number of logs
+// suppressed since the previous one, under "dropped". The logs below the level aren't counted.
+func LogEvery(ctx context.Context, interval time.Duration, level LogLevel, msg string, fields ...zap.Field) {
+	ce := getCtxLogger(ctx).Check(level, msg)
+	if ce == nil {
+		return
+	}
+	dropped, ok := allowLogEvery(callSite(), interval)
+	if !ok {
+		return
+	}
+	if dropped > 0 {
+		fields = append(fields[:len(fields):len(fields)], zap.Uint64(DroppedKey, dropped))
+	}
+	ce.Write(fields...)
+}
+
+// callSite - Return the call site of the function calling callSite.
+func callSite() callSiteKey {
+	var pcs [1]uintptr
+	// skip runtime.Callers, callSite and its caller
+	runtime.Callers(3, pcs[:])
+	// the frame rather than the pc, which differs at each place where the caller is inlined
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	return callSiteKey{file: frame.File, line: frame.Line}
+}
+
+// allowLogEvery - Return whether the call site key logs now, and the number of logs it suppressed before.
+func allowLogEvery(key callSiteKey, interval time.Duration) (uint64, bool) {
+	now := logEveryNow()
+	logEveryMutex.Lock()
+	defer logEveryMutex.Unlock()
+
+	site, ok := logEverySites[key]
+	// a clock set back counts as elapsed, rather than suppressing the logs until it catches up
+	if ok && !now.Before(site.last) && now.Sub(site.last) < interval {
+		site.dropped++
+		return 0, false
+	}
+	if !ok {
+		if len(logEverySites) >= maxLogEverySites {
+			evictLogEverySites(now)
+		}
+		site = &logEverySite{}
+		logEverySites[key] = site
+	}
+	dropped := site.dropped
+	site.last, site.interval, site.dropped = now, interval, 0
+	return dropped, true
+}
+
+// evictLogEverySites - Forget the call sites whose interval has elapsed, or all of them if none has.
+// The logs they suppressed are then not counted.
+func evictLogEverySites(now time.Time) {
+	for key, site := range logEverySites {
+		if now.Before(site.last) || now.Sub(site.last) >= site.interval {
+			delete(logEverySites, key)
+		}
+	}
+	if len(logEverySites) >= maxLogEverySites {
+		logEverySites = map[callSiteKey]*logEverySite{}
+	}
+}
+
+// LogFirstN - Log msg in the given level only the first n times for each call site in the life of the process,
+// e.g. for a warning at startup. The logs below the level aren't counted. Beyond 4096 call sites,
+// the new ones don't log, rather than logging without limit.
+func LogFirstN(ctx context.Context, n int, level LogLevel, msg string, fields ...zap.Field) {
+	if n <= 0 {
+		return
+	}
+	ce := getCtxLogger(ctx).Check(level, msg)
+	if ce == nil {
+		return
+	}
+	if count := logFirstNCount(callSite()); count == nil || count.Inc() > uint64(n) {
+		return
+	}
+	ce.Write(fields...)
+}
+
+// logFirstNCount - Return the counter of the logs of the call site key, nil if there are too many call sites.
+func logFirstNCount(key callSiteKey) *atomic.Uint64 {
+	logFirstNMutex.Lock()
+	defer logFirstNMutex.Unlock()
+	count, ok := logFirstNCounts[key]
+	if !ok && len(logFirstNCounts) < maxLogEverySites {
+		count = &atomic.Uint64{}
+		logFirstNCounts[key] = count
+	}
+	return count
+}
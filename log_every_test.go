@@ -0,0 +1,134 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+// resetLogEverySites - Forget the call sites of LogEvery before and after the test, e.g. for -count.
+func resetLogEverySites(t *testing.T) {
+	reset := func() {
+		logEveryMutex.Lock()
+		logEverySites = map[callSiteKey]*logEverySite{}
+		logEveryMutex.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestLogEvery(t *testing.T) {
+	resetLogEverySites(t)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { logEveryNow = f }(logEveryNow)
+	logEveryNow = func() time.Time { return now }
+
+	l, read := newTestLogger(t, &Config{})
+	ctx := WithLogger(context.Background(), l)
+	hot := func() {
+		LogEvery(ctx, 5*time.Second, ErrorLvl, "hot error", zap.Int("k", 1))
+	}
+	for i := 0; i < 5; i++ {
+		hot()
+	}
+	// another call site isn't suppressed by the first one
+	LogEvery(ctx, 5*time.Second, ErrorLvl, "other error")
+	now = now.Add(4 * time.Second)
+	hot()
+	// below the level, neither logged nor counted
+	LogEvery(ctx, 5*time.Second, DebugLvl, "debug")
+	now = now.Add(time.Second)
+	hot()
+	hot()
+	// the clock set back
+	now = now.Add(-time.Hour)
+	hot()
+	hot()
+
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[0], `/log_every_test.go:`)
+	assert.True(t, strings.HasSuffix(lines[0], `|hot error|{"k":1}`), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], `|other error`), lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], `|hot error|{"k":1,"dropped":5}`), lines[2])
+	assert.True(t, strings.HasSuffix(lines[3], `|hot error|{"k":1,"dropped":1}`), lines[3])
+}
+
+func TestLogEveryBounded(t *testing.T) {
+	resetLogEverySites(t)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { logEveryNow = f }(logEveryNow)
+	logEveryNow = func() time.Time { return now }
+
+	for line := 1; line <= maxLogEverySites; line++ {
+		_, ok := allowLogEvery(callSiteKey{file: "hot.go", line: line}, time.Minute)
+		assert.True(t, ok)
+	}
+	_, ok := allowLogEvery(callSiteKey{file: "hot.go", line: maxLogEverySites + 1}, time.Minute)
+	assert.True(t, ok)
+	logEveryMutex.Lock()
+	assert.True(t, len(logEverySites) <= maxLogEverySites)
+	logEveryMutex.Unlock()
+}
+
+// resetLogFirstNCounts - Forget the call sites of LogFirstN before and after the test, e.g. for -count.
+func resetLogFirstNCounts(t *testing.T) {
+	reset := func() {
+		logFirstNMutex.Lock()
+		logFirstNCounts = map[callSiteKey]*atomic.Uint64{}
+		logFirstNMutex.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestLogFirstN(t *testing.T) {
+	resetLogFirstNCounts(t)
+	l, read := newTestLogger(t, &Config{})
+	ctx := WithLogger(context.Background(), l)
+	for i := 0; i < 3+5; i++ {
+		LogFirstN(ctx, 3, WarnLvl, "startup warning", zap.Int("i", i))
+		// below the level, not counted
+		LogFirstN(ctx, 1, DebugLvl, "debug")
+	}
+	LogFirstN(ctx, 0, WarnLvl, "never")
+	LogFirstN(ctx, -1, WarnLvl, "never")
+	logFirstNMutex.Lock()
+	// only the warning is tracked, neither the logs below the level nor the calls never logging
+	assert.Len(t, logFirstNCounts, 1)
+	logFirstNMutex.Unlock()
+
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Len(t, lines, 3)
+	for i, line := range lines {
+		assert.Contains(t, line, `/log_every_test.go:`)
+		assert.True(t, strings.HasSuffix(line, fmt.Sprintf(`|startup warning|{"i":%d}`, i)), line)
+	}
+}
+
+func TestLogFirstNTooManySites(t *testing.T) {
+	resetLogFirstNCounts(t)
+	logFirstNMutex.Lock()
+	for i := 0; i < maxLogEverySites; i++ {
+		logFirstNCounts[callSiteKey{file: "other.go", line: i}] = &atomic.Uint64{}
+	}
+	logFirstNMutex.Unlock()
+
+	l, read := newTestLogger(t, &Config{})
+	ctx := WithLogger(context.Background(), l)
+	for i := 0; i < 3; i++ {
+		LogFirstN(ctx, 1, WarnLvl, "new site")
+	}
+	l.Info("after")
+	// the new call site isn't tracked, and doesn't log
+	assert.NotContains(t, read(), "new site")
+	logFirstNMutex.Lock()
+	assert.Len(t, logFirstNCounts, maxLogEverySites)
+	logFirstNMutex.Unlock()
+}
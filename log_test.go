@@ -0,0 +1,152 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caser789/logger/internal/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatHelpers(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	ctx := WithLogger(context.Background(), l)
+	Infof(ctx, "x=%d", 5)
+	Warnf(ctx, "name=%s", "abc")
+	out := read()
+	assert.Contains(t, out, "|x=5\n")
+	assert.Contains(t, out, "|name=abc\n")
+
+	config := &Config{Path: t.TempDir()}
+	tracingLoggerInitOnce.Do(func() {})
+	initTracingLogger(config)
+	Tracingf(ctx, "y=%d", 6)
+	_ = tracingLogger.Load().Sync()
+	data, err := os.ReadFile(filepath.Join(config.Path, DefaultTracingFileName+".log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "|y=6\n")
+}
+
+func TestTracingLogSampling(t *testing.T) {
+	config := &Config{Path: t.TempDir()}
+	tracingLoggerInitOnce.Do(func() {})
+	initTracingLogger(config)
+	defer tracingLogSampling.Store(tracingLogSampling.Load())
+
+	sampled, notSampled := true, false
+	scg := trace.NewSpanContextGenerator("")
+	sampledCtx := WithSpanContext(context.Background(), scg.NewSpanContext(trace.IsSampled(&sampled)))
+	criticalCtx := WithSpanContext(context.Background(), scg.NewSpanContext(trace.IsSampled(&notSampled), trace.IsCritical(true)))
+
+	Tracing(sampledCtx, "disabled")
+	tracingLogSampling.Store(true)
+	Tracing(sampledCtx, "sampled")
+	Tracing(criticalCtx, "critical")
+	_ = tracingLogger.Load().Sync()
+
+	data, err := os.ReadFile(filepath.Join(config.Path, DefaultTracingFileName+".log"))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasSuffix(lines[0], "|disabled"), lines[0])
+	assert.Contains(t, lines[1], `"sampled":true,"sample_reason":"sampled"`)
+	assert.Contains(t, lines[2], `"sampled":false,"sample_reason":"critical"`)
+}
+
+func TestTracingSpanFields(t *testing.T) {
+	config := &Config{Path: t.TempDir()}
+	tracingLoggerInitOnce.Do(func() {})
+	initTracingLogger(config)
+	defer tracingSpanFields.Store(tracingSpanFields.Load())
+	defer tracingLogSampling.Store(tracingLogSampling.Load())
+	defer alwaysRecordTracing.Store(alwaysRecordTracing.Load())
+	alwaysRecordTracing.Store(true)
+	tracingLogSampling.Store(false)
+	tracingSpanFields.Store(true)
+
+	// the type marker is in the 3 high bits of the flag ending the trace id
+	newCtx := func(flag byte) context.Context {
+		sc, err := trace.NewSpanContextFromBytes(append(bytes.Repeat([]byte{1}, 15), flag, 1, 0, 0, 0, 0, 0, 0, 1,
+			0, 0, 0, 0, 0, 0, 0, 0))
+		assert.Nil(t, err)
+		return WithSpanContext(context.Background(), sc)
+	}
+	Tracing(newCtx(0<<5|1<<1), "old format")
+	Tracing(newCtx(1<<5|1<<1|1<<2), "normal")
+	Tracing(newCtx(2<<5), "debug")
+	Tracing(newCtx(3<<5|1<<1), "stress test")
+	Tracing(newCtx(4<<5|1<<1), "shadow")
+	Tracing(newCtx(7<<5), "unknown")
+	Tracing(context.Background(), "without span context")
+	tracingLogSampling.Store(true)
+	Tracing(newCtx(3<<5), "with sampling")
+	_ = tracingLogger.Load().Sync()
+
+	data, err := os.ReadFile(filepath.Join(config.Path, DefaultTracingFileName+".log"))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 8)
+	assert.Contains(t, lines[0], `{"req_type":"old_format","sampled":true,"critical":false}`)
+	assert.Contains(t, lines[1], `{"req_type":"normal","sampled":true,"critical":true}`)
+	assert.Contains(t, lines[2], `{"req_type":"debug","sampled":true,"critical":false}`)
+	assert.Contains(t, lines[3], `{"req_type":"stress_test","sampled":true,"critical":false}`)
+	assert.Contains(t, lines[4], `{"req_type":"shadow","sampled":true,"critical":false}`)
+	assert.Contains(t, lines[5], `{"req_type":"unknown","sampled":false,"critical":false}`)
+	assert.True(t, strings.HasSuffix(lines[6], "|without span context"), lines[6])
+	assert.Contains(t, lines[7], `{"sampled":false,"sample_reason":"not_sampled","req_type":"stress_test","critical":false}`)
+}
+
+func TestHelperCaller(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	ctx := WithLogger(context.Background(), l)
+	Info(ctx, "helper")
+	Infof(ctx, "helper %s", "sugar")
+	LogWithSeq(ctx, 1, InfoLvl, "helper seq")
+	l.Info("direct")
+
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Len(t, lines, 4)
+	for _, line := range lines {
+		assert.Contains(t, line, "/log_test.go:", line)
+		assert.NotContains(t, line, "/log.go:", line)
+	}
+}
+
+func TestTracingDropsUnsampledTraces(t *testing.T) {
+	config := &Config{Path: t.TempDir()}
+	tracingLoggerInitOnce.Do(func() {})
+	initTracingLogger(config)
+	defer alwaysRecordTracing.Store(alwaysRecordTracing.Load())
+	defer tracingLogSampling.Store(tracingLogSampling.Load())
+	alwaysRecordTracing.Store(false)
+	tracingLogSampling.Store(false)
+
+	sampled, notSampled := true, false
+	scg := trace.NewSpanContextGenerator("")
+	sampledCtx := WithSpanContext(context.Background(), scg.NewSpanContext(trace.IsSampled(&sampled)))
+	unsampledCtx := WithSpanContext(context.Background(), scg.NewSpanContext(trace.IsSampled(&notSampled)))
+
+	Tracing(unsampledCtx, "unsampled")
+	Tracingf(unsampledCtx, "unsampled %s", "sugar")
+	Tracing(sampledCtx, "sampled")
+	Tracing(context.Background(), "without trace")
+	tracingLogSampling.Store(true)
+	Tracing(unsampledCtx, "audited")
+	tracingLogSampling.Store(false)
+	alwaysRecordTracing.Store(true)
+	Tracing(unsampledCtx, "always recorded")
+	_ = tracingLogger.Load().Sync()
+
+	data, err := os.ReadFile(filepath.Join(config.Path, DefaultTracingFileName+".log"))
+	assert.Nil(t, err)
+	out := string(data)
+	assert.NotContains(t, out, "|unsampled")
+	assert.Contains(t, out, "|sampled\n")
+	assert.Contains(t, out, "|without trace\n")
+	assert.Contains(t, out, "|audited|")
+	assert.Contains(t, out, "|always recorded\n")
+}
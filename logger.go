@@ -1,9 +1,15 @@
 package log
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
 	"sync"
+	stdatomic "sync/atomic"
 	"time"
 
 	"github.com/caser789/logger/internal/extension"
@@ -19,6 +25,11 @@ import (
 type LogLevel = zapcore.Level
 type SplitLevel string
 type PrintToStd uint8
+type RotationMode string
+type LevelEncoder string
+type TimeEncoding string
+type QueueFullPolicy string
+type Encoding string
 
 const (
 	DebugLvl                      = zapcore.DebugLevel
@@ -38,6 +49,36 @@ const (
 	PrintToStd_SYSLOG  PrintToStd = 2
 	PrintToStd_TRACING PrintToStd = 4
 	PrintToStd_ALL     PrintToStd = 7
+	// RotationRename rotates log files by renaming them, which is the default.
+	RotationRename RotationMode = "rename"
+	// RotationCopyTruncate rotates log files by copying and truncating them in place, for network filesystems.
+	RotationCopyTruncate RotationMode = "copytruncate"
+	// LevelEncoderLowercase writes levels as "info", which is the default.
+	LevelEncoderLowercase LevelEncoder = "lowercase"
+	// LevelEncoderCapital writes levels as "INFO".
+	LevelEncoderCapital LevelEncoder = "capital"
+	// LevelEncoderNumeric writes levels as numeric codes: debug=10, info=20, warn=30, error=40, and so on.
+	LevelEncoderNumeric LevelEncoder = "numeric"
+	// TimeEncodingLayout writes times with Config.TimeLayout, which is the default.
+	TimeEncodingLayout TimeEncoding = "layout"
+	// TimeEncodingEpoch writes times as seconds since the epoch, with a fraction, e.g. 1717200000.123456.
+	TimeEncodingEpoch TimeEncoding = "epoch"
+	// TimeEncodingEpochMillis writes times as integer milliseconds since the epoch, e.g. 1717200000123.
+	TimeEncodingEpochMillis TimeEncoding = "epochMillis"
+	// TimeEncodingISO8601 writes times as ISO8601 strings with millisecond precision, e.g. 2006-01-02T15:04:05.000Z0700.
+	TimeEncodingISO8601 TimeEncoding = "iso8601"
+	// EncodingConsole writes the logs as columns separated by Config.ConsoleSeparator, then the fields in JSON,
+	// which is the default.
+	EncodingConsole Encoding = "console"
+	// EncodingJSON writes the logs as JSON objects, with the columns under "ts", "level", "caller", TraceKey
+	// and "msg", for the log collectors parsing JSON.
+	EncodingJSON Encoding = "json"
+	// QueueFullDrop drops new logs when an async queue is full, which is the default.
+	QueueFullDrop QueueFullPolicy = "drop"
+	// QueueFullBlock blocks the caller until there is room in the queue, for logs which must not be lost (e.g. audit).
+	QueueFullBlock QueueFullPolicy = "block"
+	// QueueFullDropOldest drops the oldest queued logs to make room for the new ones.
+	QueueFullDropOldest QueueFullPolicy = "drop-oldest"
 )
 
 const (
@@ -48,21 +89,30 @@ const (
 	SysErrorLogFileName    = "sys_error"
 	DefaultLogFileName     = "server"
 	DefaultTracingFileName = "traffic_recording"
+	AccessLogFileName      = "access"
+	AuditLogFileName       = "audit"
+	RegionKey              = "region"
+	RunIDKey               = "run_id"
+	HostKey                = "host"
+	PIDKey                 = "pid"
 )
 
 var (
-	logger        *zap.Logger
-	sysLogger     *zap.Logger
-	tracingLogger *zap.Logger
+	// the package loggers are swapped atomically by ReinitLogger and RotateToDatedDir
+	logger        stdatomic.Pointer[zap.Logger]
+	sysLogger     stdatomic.Pointer[zap.Logger]
+	tracingLogger stdatomic.Pointer[zap.Logger]
+	accessLogger  stdatomic.Pointer[zap.Logger]
+	auditLogger   stdatomic.Pointer[zap.Logger]
 
 	loggerInitOnce        sync.Once
 	sysLoggerInitOnce     sync.Once
 	tracingLoggerInitOnce sync.Once
+	accessLoggerInitOnce  sync.Once
+	auditLoggerInitOnce   sync.Once
 
-	logLevel        atomic.Int32
-	initialLogLevel LogLevel
-
-	resetVer atomic.Int64
+	// loggerInitialized is set once the loggers using nameMap are built, see SetLogFileName.
+	loggerInitialized atomic.Bool
 
 	levelMap = map[SplitLevel]LogLevel{SplitDebug: DebugLvl, SplitInfo: InfoLvl, SplitWarn: WarnLvl, SplitError: ErrorLvl}
 	nameMap  = map[LogLevel]string{DebugLvl: "debug", InfoLvl: "info", WarnLvl: "warn", ErrorLvl: "error"}
@@ -71,27 +121,190 @@ var (
 type Config struct {
 	// Level - Initial default log level.
 	// By default, DebugLvl is set for non-live environment, while InfoLvl is set for live environment.
-	Level LogLevel
+	Level LogLevel `json:"level" yaml:"level"`
 	//Deprecated -Print all logs into stdout.Deprecated,can use PrintToStd to make sure which kind log you want to see in stdout.
-	PrintToStdout bool
+	PrintToStdout bool `json:"printToStdout" yaml:"printToStdout"`
 	// PrintToStdout - Which kind log you want print into stdout,default none.Only effect in the non-live environment
-	PrintToStd PrintToStd
-	Compress   bool
-	// Path - Customized log file path.Only effect in K8S. Log files will be created under ./log dir if not specified.
-	Path string
+	PrintToStd PrintToStd `json:"printToStd" yaml:"printToStd"`
+	Compress   bool       `json:"compress" yaml:"compress"`
+	// CompressionLevel - The gzip level used when Compress is set, from 1 (best speed) to 9 (best compression),
+	// or -2 (gzip.HuffmanOnly). 0 means the gzip default level, so gzip.NoCompression can't be chosen:
+	// disable Compress instead. It will be the gzip default level if not specified.
+	CompressionLevel int `json:"compressionLevel" yaml:"compressionLevel"`
+	// Path - Customized log file path.Only effect in K8S. Log files will be created under the LOG_DIR env dir, or ./log, if not specified.
+	Path string `json:"path" yaml:"path"`
 	// LogFileName - Customized log file name. It will be server.log if not specified.
-	LogFileName string
+	LogFileName string `json:"logFileName" yaml:"logFileName"`
 	// SplitLevel -The minimum level of logs to be split. Logs greater than this level will write into different file.
 	//Logs less than this level will write into server.log,and all log will write into server.log if not set.
-	SplitLevel SplitLevel
+	SplitLevel SplitLevel `json:"splitLevel" yaml:"splitLevel"`
+	// RouteFunc - Return the file name (without .log, in Path) of the logs at a level, and false to drop them.
+	// When set, it replaces SplitLevel, e.g. to write warn and error together apart from info. It is called
+	// for every log, so it must be cheap and always return the same file for a level. It can only be set in code.
+	// The logs routed to an empty name or a file of the other logs (sys, access, audit, tracing...) are written
+	// into LogFileName.
+	RouteFunc func(LogLevel) (filename string, write bool) `json:"-" yaml:"-"`
 	//TracingLogFileName -Customized tracing log file.It will be traffic_recording.log if not specified
-	TracingLogFileName string
+	TracingLogFileName string `json:"tracingLogFileName" yaml:"tracingLogFileName"`
+	// FlushInterval - How often buffered logs are flushed to the log files, 10ms if not specified.
+	// A longer interval trades latency for throughput.
+	FlushInterval time.Duration `json:"flushInterval" yaml:"flushInterval"`
+	// RotateInterval - Split logs into one file per interval, e.g. server-2024060114.log with time.Hour.
+	// Files still rotate within an interval when exceeding the size limit. Default off.
+	RotateInterval time.Duration `json:"rotateInterval" yaml:"rotateInterval"`
+	// RotationMode - How log files are rotated, RotationRename if not specified.
+	// Use RotationCopyTruncate on network filesystems (e.g. NFS) so the log file keeps its inode.
+	RotationMode RotationMode `json:"rotationMode" yaml:"rotationMode"`
+	// SyslogAddr - Send logs to the syslog daemon at this address instead of the log files,
+	// e.g. udp://127.0.0.1:514, tcp://127.0.0.1:514 or unix:///dev/log. Logs are written into files
+	// if the daemon can't be reached.
+	SyslogAddr string `json:"syslogAddr" yaml:"syslogAddr"`
+	// UseJournald - Send logs to journald instead of the log files, each field as a journald variable
+	// (e.g. the trace id as TRACE_ID) so journalctl can filter on them, and the level as the priority.
+	// The fields named like the variables of journald are prefixed by FIELD_ (e.g. FIELD_PRIORITY), and those
+	// starting with a digit are dropped. Only supported on linux. Logs are written into files if journald can't be reached.
+	UseJournald bool `json:"useJournald" yaml:"useJournald"`
+	// KafkaBrokers and KafkaTopic - Send logs to this Kafka topic instead of the log files.
+	// Requires a producer registered with RegisterKafkaProducer. Logs are queued, and handled according to
+	// QueueFullPolicy if the producer can't keep up.
+	KafkaBrokers []string `json:"kafkaBrokers" yaml:"kafkaBrokers"`
+	KafkaTopic   string   `json:"kafkaTopic" yaml:"kafkaTopic"`
+	// QueueFullPolicy - What to do with new logs when an async queue (of the Kafka sink or Async) is full,
+	// QueueFullDrop if not specified. The counters are returned by AsyncQueueStats.
+	QueueFullPolicy QueueFullPolicy `json:"queueFullPolicy" yaml:"queueFullPolicy"`
+	// Async - Encode and write the logs in the background, so logging only queues them, to protect the latency
+	// of the callers. The logs are handled according to QueueFullPolicy when the queue is full, and counted by
+	// AsyncQueueStats. Sync writes the queued logs. The logs above error level are still written synchronously.
+	// The values referred to by the fields (e.g. of zap.Reflect) must not be modified after logging. Default off.
+	Async bool `json:"async" yaml:"async"`
+	// AsyncQueueSize - The number of logs the queue of each log file holds with Async, 10000 if not specified.
+	AsyncQueueSize int `json:"asyncQueueSize" yaml:"asyncQueueSize"`
+	// TraceFirst - Put the trace id column at the beginning of each line in the tracing log file.
+	// Other log files keep the trace id after the caller.
+	TraceFirst bool `json:"traceFirst" yaml:"traceFirst"`
+	// SampleByField - Sample the user logs per value of this field (e.g. customer_id), so a noisy value doesn't
+	// drown the others. Within each second, the first 100 logs of a value are written, then every 100th.
+	// Logs without the field are not sampled. Default off.
+	SampleByField string `json:"sampleByField" yaml:"sampleByField"`
+	// LevelEncoder - How the level column is written, LevelEncoderLowercase if not specified.
+	LevelEncoder LevelEncoder `json:"levelEncoder" yaml:"levelEncoder"`
+	// Encoding - How the logs are written, EncodingConsole if not specified.
+	Encoding Encoding `json:"encoding" yaml:"encoding"`
+	// JSONSeq - Frame the logs of EncodingJSON as json-seq records (RFC 7464), each prefixed with the RS byte
+	// (0x1E) and ended with "\n", so a stream can be parsed unambiguously even with embedded newlines.
+	// It doesn't apply to EncodingConsole. Default off.
+	JSONSeq bool `json:"jsonSeq" yaml:"jsonSeq"`
+	// TimeEncoding - How the time column is written, TimeEncodingLayout if not specified.
+	TimeEncoding TimeEncoding `json:"timeEncoding" yaml:"timeEncoding"`
+	// UseUTC - Write the time column in UTC instead of the local time zone. The names of the rotated files
+	// are always timestamped in UTC. Default off.
+	UseUTC bool `json:"useUTC" yaml:"useUTC"`
+	// TimeLayout - The layout of the time column with TimeEncodingLayout, e.g. time.RFC3339Nano.
+	// Default "2006-01-02 15:04:05.999999-07:00".
+	TimeLayout string `json:"timeLayout" yaml:"timeLayout"`
+	// SpanBatchSize - Write the spans of the logging tracer (see EnableLoggingTracer) into the tracing log
+	// in batches of this size, instead of one entry per span. Batches are also written every second and by Sync.
+	SpanBatchSize int `json:"spanBatchSize" yaml:"spanBatchSize"`
+	// DisableStacktrace - Never write stacktraces into the logs, including the stack of errors logged with ErrorField,
+	// for environments where they must not be persisted. Default off.
+	DisableStacktrace bool `json:"disableStacktrace" yaml:"disableStacktrace"`
+	// StacktraceLevel - The lowest level of the logs with a stacktrace, PanicLvl if nil.
+	StacktraceLevel *LogLevel `json:"stacktraceLevel" yaml:"stacktraceLevel"`
+	// DeepRedactKeys - Write the values of the keys matching these patterns (e.g. "password" or "*_token",
+	// see path.Match) as "***", case insensitively, including the keys of nested objects and structs logged
+	// with zap.Any or zap.Reflect. Default off.
+	DeepRedactKeys []string `json:"deepRedactKeys" yaml:"deepRedactKeys"`
+	// MaxReflectedBytes - Write the values encoded by reflection (e.g. zap.Any of a struct) longer than this size
+	// as "...(too large)". Default off.
+	MaxReflectedBytes int `json:"maxReflectedBytes" yaml:"maxReflectedBytes"`
+	// MaxReflectedDepth - Write the values encoded by reflection nested deeper than this depth, and the cyclic ones,
+	// as "...(too deep)". Default off.
+	MaxReflectedDepth int `json:"maxReflectedDepth" yaml:"maxReflectedDepth"`
+	// AlsoStdout - Print the logs into stdout on top of writing them into the log files, e.g. in development.
+	// Default off.
+	AlsoStdout bool `json:"alsoStdout" yaml:"alsoStdout"`
+	// ConsoleSeparator - The separator of the columns of the logs, e.g. "\t" for the parsers expecting tabs.
+	// Default "|".
+	ConsoleSeparator string `json:"consoleSeparator" yaml:"consoleSeparator"`
+	// EmitTraceAsField - Also write the trace id under "@jiao_trace_id" in the {...} block of the fields,
+	// for the log consumers only parsing the block. Default off, it is only written in its column.
+	EmitTraceAsField bool `json:"emitTraceAsField" yaml:"emitTraceAsField"`
+	// FieldPrefix - Prepend this prefix to the keys of the fields, e.g. "payments." to log payments.id,
+	// so they don't collide with the fields of other services in the log backend. Default none.
+	FieldPrefix string `json:"fieldPrefix" yaml:"fieldPrefix"`
+	// HumanReadableDurations - Write the durations like 1.5s or 250ms instead of a number of milliseconds.
+	// It doesn't apply to EncodingJSON. Default off.
+	HumanReadableDurations bool `json:"humanReadableDurations" yaml:"humanReadableDurations"`
+	// DisableCaller - Don't write the caller column, to save the cost of resolving it. Default off.
+	DisableCaller bool `json:"disableCaller" yaml:"disableCaller"`
+	// TailSize - Keep the last TailSize lines of the user logs in memory, served by TailHandler. Default off.
+	TailSize int `json:"tailSize" yaml:"tailSize"`
+	// WithRegion - Attach the region read from the REGION or DATACENTER env to every log. Default off.
+	WithRegion bool `json:"withRegion" yaml:"withRegion"`
+	// FieldHints - Group the fields wrapped by IndexedField and StoredField into "indexed" and "stored" objects,
+	// so the log backend only indexes the former. Default off, the wrapped fields are written like any other.
+	FieldHints bool `json:"fieldHints" yaml:"fieldHints"`
+	// TracingLogSampling - Attach to every tracing log whether its trace is sampled, under "sampled",
+	// and why, under "sample_reason" (e.g. "critical"), to audit the sampling decisions. Default off.
+	// It implies AlwaysRecordTracing, as the tracing logs of the traces which are not sampled are audited too.
+	TracingLogSampling bool `json:"tracingLogSampling" yaml:"tracingLogSampling"`
+	// TracingSpanFields - Attach to every tracing log with a span context the request type of its trace,
+	// under "req_type" (e.g. "stress_test"), and whether it is sampled and critical, under "sampled" and "critical",
+	// to analyze the traffic recordings by them. Default off.
+	TracingSpanFields bool `json:"tracingSpanFields" yaml:"tracingSpanFields"`
+	// AlwaysRecordTracing - Write the tracing logs of the traces which are not sampled too.
+	// By default, they are dropped unless TracingLogSampling is set, while the tracing logs without a trace are written.
+	AlwaysRecordTracing bool `json:"alwaysRecordTracing" yaml:"alwaysRecordTracing"`
+	// MinLevelInLive - The lowest level that can be set in the live environment without a duration, see SetLevel.
+	// Default info.
+	MinLevelInLive LogLevel `json:"minLevelInLive" yaml:"minLevelInLive"`
+	// MaxLineBytes - Truncate the encoded log lines longer than this size, ending them with "...(truncated)",
+	// for the sinks rejecting long lines (e.g. 64KB for UDP syslog). Default off.
+	MaxLineBytes int `json:"maxLineBytes" yaml:"maxLineBytes"`
+	// MaxMessageBytes - Cut the log messages longer than this size, without splitting a multi-byte character,
+	// ending them with "...(truncated)", so a huge message doesn't push the fields out of MaxLineBytes. Default off.
+	MaxMessageBytes int `json:"maxMessageBytes" yaml:"maxMessageBytes"`
+	// MaxFieldBytes - Cut the string values of the fields longer than this size like MaxMessageBytes,
+	// e.g. for the response bodies or stack dumps logged as fields. The keys are never cut. Default off.
+	MaxFieldBytes int `json:"maxFieldBytes" yaml:"maxFieldBytes"`
+	// WithRunID - Attach a random id generated once per process to every log, under "run_id",
+	// to tell apart the logs of the successive runs of a service sharing a file. Default off.
+	WithRunID bool `json:"withRunID" yaml:"withRunID"`
+	// IncludeHostPID - Attach the hostname ("unknown" if it can't be read), under "host", and the process id,
+	// under "pid", to every log, to tell apart the sources of the logs aggregated from many pods. Default off.
+	IncludeHostPID bool `json:"includeHostPID" yaml:"includeHostPID"`
+	// GlobalFields - Attach these fields to every log of the user, system, tracing and access loggers,
+	// e.g. zap.String("service", "payments"). They can only be set in code. Default none.
+	GlobalFields []zap.Field `json:"-" yaml:"-"`
+	// IncludeSequence - Attach a sequence number, under "seq", increasing by one with each log of the user, system,
+	// tracing and access loggers, each logger having its own, to detect the lines lost by the log shipping.
+	// The split log files of a logger share its sequence. Default off.
+	IncludeSequence bool `json:"includeSequence" yaml:"includeSequence"`
 }
 
 // InitLogger - Initialize the logger and system logger.
 // This function should only run once.
+// The errors of config are printed into stderr and fixed or ignored, use InitLoggerE to handle them.
 func InitLogger(config *Config) {
-	initLogLevel(config)
+	if err := validateConfig(config); err != nil {
+		printStderr("init the loggers with an invalid config, some fields are ignored: %v\n", err)
+	}
+	initLoggers(config)
+}
+
+// InitLoggerE - Like InitLogger, but return the errors of config, e.g. a log file name reserved by the other logs
+// or an unknown split level, without initializing the loggers.
+func InitLoggerE(config *Config) error {
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	initLoggers(config)
+	return nil
+}
+
+func initLoggers(config *Config) {
+	applySettings(config)
+	setInitConfig(config)
 
 	loggerInitOnce.Do(func() {
 		// init default logger
@@ -107,6 +320,26 @@ func InitLogger(config *Config) {
 		// init tracing logger
 		initTracingLogger(config)
 	})
+
+	accessLoggerInitOnce.Do(func() {
+		// init access logger
+		initAccessLogger(config)
+	})
+
+	auditLoggerInitOnce.Do(func() {
+		// init audit logger
+		initAuditLogger(config)
+	})
+}
+
+// applySettings - Apply the level and the package wide settings of config.
+func applySettings(config *Config) {
+	minLevelInLive.Store(int32(config.MinLevelInLive))
+	initLogLevel(config)
+	stacktraceDisabled.Store(config.DisableStacktrace)
+	tracingLogSampling.Store(config.TracingLogSampling)
+	alwaysRecordTracing.Store(config.AlwaysRecordTracing)
+	tracingSpanFields.Store(config.TracingSpanFields)
 }
 
 func initLogLevel(config *Config) {
@@ -114,7 +347,6 @@ func initLogLevel(config *Config) {
 	if config.Level > lvl {
 		lvl = config.Level
 	}
-	initialLogLevel = lvl
 	SetLevel(lvl, 0)
 }
 
@@ -126,7 +358,7 @@ func GetLogger() *zap.Logger {
 		initLogLevel(config)
 		initDefaultLogger(config)
 	})
-	return logger
+	return logger.Load()
 }
 
 // GetSysLogger - Return system logger.
@@ -143,7 +375,7 @@ func GetSysLogger() *zap.Logger {
 		},
 	)
 
-	return sysLogger
+	return sysLogger.Load()
 }
 
 func GetTracingLogger() *zap.Logger {
@@ -155,10 +387,80 @@ func GetTracingLogger() *zap.Logger {
 			}
 			initTracingLogger(config)
 		})
-	return tracingLogger
+	return tracingLogger.Load()
+}
+
+// GetAccessLogger - Return the access logger. The output log will be in the ./log/access.log file.
+func GetAccessLogger() *zap.Logger {
+	accessLoggerInitOnce.Do(
+		func() {
+			config := &Config{
+				Level: InfoLvl,
+			}
+			initAccessLogger(config)
+		})
+	return accessLogger.Load()
+}
+
+// GetAuditLogger - Return the audit logger, see Audit. The output log will be in the ./log/audit.log file.
+func GetAuditLogger() *zap.Logger {
+	auditLoggerInitOnce.Do(
+		func() {
+			config := &Config{
+				Level: InfoLvl,
+			}
+			initAuditLogger(config)
+		})
+	return auditLogger.Load()
+}
+
+// SyncDefault - Flush the logger only, see GetLogger.
+func SyncDefault() error {
+	return GetLogger().Sync()
+}
+
+// SyncSys - Flush the system logger only, see GetSysLogger.
+func SyncSys() error {
+	return GetSysLogger().Sync()
+}
+
+// SyncTracing - Flush the tracing logger only, including the spans of the logging tracer, see GetTracingLogger.
+func SyncTracing() error {
+	flushSpanLogs()
+	return GetTracingLogger().Sync()
+}
+
+// SyncAccess - Flush the access logger only, see GetAccessLogger.
+func SyncAccess() error {
+	return GetAccessLogger().Sync()
+}
+
+// SyncAudit - Flush the audit logger only, see GetAuditLogger.
+func SyncAudit() error {
+	return GetAuditLogger().Sync()
+}
+
+// SyncNamed - Flush one logger by name: "default", "sys", "tracing", "access" or "audit", e.g. to make sure
+// some logs are persisted before a critical step without flushing the others.
+func SyncNamed(name string) error {
+	switch name {
+	case "default":
+		return SyncDefault()
+	case "sys":
+		return SyncSys()
+	case "tracing":
+		return SyncTracing()
+	case "access":
+		return SyncAccess()
+	case "audit":
+		return SyncAudit()
+	default:
+		return fmt.Errorf("log: unknown logger %q", name)
+	}
 }
 
 func Sync() error {
+	flushSpanLogs()
 	var res *multierror.Error
 	if err := GetLogger().Sync(); err != nil {
 		res = multierror.Append(res, err)
@@ -169,10 +471,24 @@ func Sync() error {
 	if err := GetTracingLogger().Sync(); err != nil {
 		res = multierror.Append(res, err)
 	}
+	if err := GetAccessLogger().Sync(); err != nil {
+		res = multierror.Append(res, err)
+	}
+	if err := GetAuditLogger().Sync(); err != nil {
+		res = multierror.Append(res, err)
+	}
 	return res
 }
 
 func initTracingLogger(config *Config) {
+	loggerInitialized.Store(true)
+	opts := tracingLoggerOptions(config)
+	tracingLogger.Store(newLogger(opts...).WithOptions(sequenceOptions(config)...).With(configFields(config)...))
+	setGlobalFiles("tracing", opts...)
+}
+
+// tracingLoggerOptions - Return the options of the tracing logger, defaulting config.TracingLogFileName.
+func tracingLoggerOptions(config *Config) []option {
 	printToStd := config.PrintToStd
 	if config.TracingLogFileName == "" {
 		config.TracingLogFileName = DefaultTracingFileName
@@ -183,45 +499,90 @@ func initTracingLogger(config *Config) {
 		}
 	}
 	var opts []option
-	if (printToStd == PrintToStd_TRACING || printToStd == PrintToStd_ALL || config.PrintToStdout) && !env.IsLive() {
-		opts = append(opts, option{
-			Stdout: true,
-			Lef: func(level zapcore.Level) bool {
-				return level >= GetLevel()
-			},
-		})
+	if (printToStd&PrintToStd_TRACING != 0 || config.PrintToStdout) && !env.IsLive() {
+		opts = append(opts, getStdoutOption(config, func(level zapcore.Level) bool {
+			return level >= GetTracingLevel()
+		}))
 	} else {
 		opts = append(opts, getOption(config, config.TracingLogFileName, func(level zapcore.Level) bool {
-			return level >= GetLevel()
+			return level >= GetTracingLevel()
 		}))
 	}
-	tracingLogger = newLogger(opts...)
+	for i := range opts {
+		opts[i].TraceFirst = config.TraceFirst
+	}
+	return opts
+}
+
+func initAccessLogger(config *Config) {
+	opt := accessLoggerOption(config)
+	accessLogger.Store(newLogger(opt).WithOptions(sequenceOptions(config)...).With(configFields(config)...))
+	setGlobalFiles("access", opt)
+}
+
+func accessLoggerOption(config *Config) option {
+	return getOption(config, AccessLogFileName, func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})
+}
+
+func initAuditLogger(config *Config) {
+	opt := auditLoggerOption(config)
+	auditLogger.Store(newLogger(opt).With(configFields(config)...))
+	setGlobalFiles("audit", opt)
+}
+
+// auditLoggerOption - Return the option of audit.log, always a file written synchronously without buffer.
+func auditLoggerOption(config *Config) option {
+	opt := getOption(config, AuditLogFileName, func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})
+	opt.Ropt.Unbuffered = true
+	opt.SyslogAddr, opt.Journald, opt.KafkaBrokers = "", false, nil
+	opt.Async = false
+	return opt
 }
 
 func initSystemLogger(config *Config) {
+	opts := sysLoggerOptions(config)
+	l := newLogger(opts...).WithOptions(sequenceOptions(config)...).With(configFields(config)...)
+	sysLogger.Store(l)
+	setGlobalFiles("sys", opts...)
+	grpczap.ReplaceGrpcLoggerV2(l)
+}
+
+func sysLoggerOptions(config *Config) []option {
 	var opts []option
 	printToStd := config.PrintToStd
-	if (printToStd == PrintToStd_SYSLOG || printToStd == PrintToStd_ALL || config.PrintToStdout) && !env.IsLive() {
-		opts = append(opts, option{
-			Stdout: true,
-			Lef: func(lvl LogLevel) bool {
-				return lvl >= GetLevel()
-			},
-		})
+	if (printToStd&PrintToStd_SYSLOG != 0 || config.PrintToStdout) && !env.IsLive() {
+		opts = append(opts, getStdoutOption(config, func(lvl LogLevel) bool {
+			return lvl >= GetSysLevel()
+		}))
 	} else {
 		opts = append(opts, getOption(config, SysErrorLogFileName, func(lvl LogLevel) bool {
 			return lvl >= ErrorLvl
 		}))
 		opts = append(opts, getOption(config, SysLogFileName, func(lvl LogLevel) bool {
-			return lvl >= GetLevel()
+			return lvl >= GetSysLevel()
 		}))
 	}
-
-	sysLogger = newLogger(opts...)
-	grpczap.ReplaceGrpcLoggerV2(sysLogger)
+	return opts
 }
 
 func initDefaultLogger(config *Config) {
+	loggerInitialized.Store(true)
+	opts := userLoggerOptions(config)
+	l := newLogger(opts...).WithOptions(configOptions(config)...).With(configFields(config)...)
+	logger.Store(l)
+	setGlobalFiles("user", opts...)
+	// a RouteFunc may drop the logs of every level, leaving no core
+	if len(opts) > 0 && !opts[0].Stdout {
+		zap.ReplaceGlobals(l)
+	}
+}
+
+// userLoggerOptions - Return the options of the user logger, defaulting config.LogFileName.
+func userLoggerOptions(config *Config) []option {
 	if config.LogFileName == "" {
 		config.LogFileName = DefaultLogFileName
 	}
@@ -231,33 +592,113 @@ func initDefaultLogger(config *Config) {
 		}
 	}
 
-	var opts []option
 	printToStd := config.PrintToStd
-	if (printToStd == PrintToStd_USERLOG || printToStd == PrintToStd_ALL || config.PrintToStdout) && !env.IsLive() {
-		printToStdOut(config)
-		return
+	if (printToStd&PrintToStd_USERLOG != 0 || config.PrintToStdout) && !env.IsLive() {
+		return []option{getStdoutOption(config, func(lvl LogLevel) bool {
+			return lvl >= GetLevel()
+		})}
 	}
 
-	splitLevel, ok := checkLevel(config.SplitLevel)
+	if config.RouteFunc != nil {
+		return getRouteOpt(config, config.RouteFunc)
+	}
+	if splitLevel, ok := checkLevel(config.SplitLevel); ok {
+		return getSplitOpt(config, splitLevel)
+	}
+	return getDefaultOpt(config)
+}
 
-	if ok {
-		opts = getSplitOpt(config, splitLevel)
-	} else {
-		opts = getDefaultOpt(config)
+// ResolveLogPaths - Return the sorted paths of the log files InitLogger writes with config,
+// e.g. for the deployment tools to collect them. The logs printed into stdout have no file,
+// and the files of the logs sent to syslog or kafka are only written when they can't be reached.
+func ResolveLogPaths(config *Config) []string {
+	c := *config
+	opts := userLoggerOptions(&c)
+	opts = append(opts, sysLoggerOptions(&c)...)
+	opts = append(opts, tracingLoggerOptions(&c)...)
+	opts = append(opts, accessLoggerOption(&c), auditLoggerOption(&c))
+
+	paths := make([]string, 0, len(opts))
+	seen := make(map[string]bool, len(opts))
+	for _, opt := range opts {
+		if opt.Stdout || seen[opt.Filename] {
+			continue
+		}
+		seen[opt.Filename] = true
+		paths = append(paths, opt.Filename)
 	}
+	sort.Strings(paths)
+	return paths
+}
 
-	logger = newLogger(opts...)
-	zap.ReplaceGlobals(logger)
+// configOptions - Return the options of the user logger according to config.
+func configOptions(config *Config) []zap.Option {
+	// numbered first, so the entries dropped by the sampling don't take a number
+	opts := sequenceOptions(config)
+	if config.TailSize > 0 {
+		tailBuf.resize(config.TailSize)
+		// encoded like the user logs (redacted, cut, etc.), as lines without the json-seq framing
+		opt := getStdoutOption(config, nil)
+		opt.JSONSeq = false
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, newTailCore(newEncoder(opt), tailBuf))
+		}))
+	}
+	if config.SampleByField != "" {
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newFieldSamplerCore(core, config.SampleByField, fieldSampleTick, fieldSampleFirst, fieldSampleThereafter)
+		}))
+	}
+	return opts
 }
 
-func printToStdOut(config *Config) {
-	opt := option{
-		Stdout: true,
-		Lef: func(lvl LogLevel) bool {
-			return lvl >= GetLevel()
-		},
+// configFields - Return the fields attached to every log according to config.
+func configFields(config *Config) []zap.Field {
+	var fields []zap.Field
+	if config.WithRegion {
+		if region := env.GetRegion(); region != "" {
+			fields = append(fields, zap.String(RegionKey, region))
+		}
+	}
+	if config.WithRunID {
+		fields = append(fields, zap.String(RunIDKey, getRunID()))
+	}
+	if config.IncludeHostPID {
+		fields = append(fields, zap.String(HostKey, getHostname()), zap.Int(PIDKey, os.Getpid()))
 	}
-	logger = newLogger(opt)
+	return append(fields, config.GlobalFields...)
+}
+
+var (
+	runID     string
+	runIDOnce sync.Once
+)
+
+// getRunID - Return the random id of this process run, see Config.WithRunID.
+func getRunID() string {
+	runIDOnce.Do(func() {
+		id := make([]byte, 8)
+		_, _ = rand.Read(id)
+		runID = hex.EncodeToString(id)
+	})
+	return runID
+}
+
+var (
+	hostname     string
+	hostnameOnce sync.Once
+)
+
+// getHostname - Return the hostname read once, or "unknown" if it can't be read, see Config.IncludeHostPID.
+func getHostname() string {
+	hostnameOnce.Do(func() {
+		name, err := os.Hostname()
+		if err != nil || name == "" {
+			name = "unknown"
+		}
+		hostname = name
+	})
+	return hostname
 }
 
 func checkLevel(splitLevel SplitLevel) (LogLevel, bool) {
@@ -271,39 +712,134 @@ func checkLevel(splitLevel SplitLevel) (LogLevel, bool) {
 }
 
 func getSplitOpt(config *Config, splitLevel LogLevel) []option {
-	var opts []option
-	//server.log
-	if splitLevel != DebugLvl {
-		opts = append(opts, getOption(config, config.LogFileName, func(lvl LogLevel) bool {
-			return lvl >= GetLevel() && lvl < splitLevel
-		}))
+	return getRouteOpt(config, func(lvl LogLevel) (string, bool) {
+		return splitFileName(config, splitLevel, lvl), true
+	})
+}
+
+// getRouteOpt - Return one option per distinct file name returned by route, in the order of the levels.
+// Each level is routed to exactly one file by route, whatever the current level:
+// the enablers only add the level threshold on top of it.
+func getRouteOpt(config *Config, userRoute func(LogLevel) (string, bool)) []option {
+	// the logs routed to a reserved file are written into config.LogFileName, see validateConfig
+	route := func(lvl LogLevel) (string, bool) {
+		name, write := userRoute(lvl)
+		if write && routeNameReserved(config, name) {
+			return config.LogFileName, true
+		}
+		return name, write
 	}
-	//split log to different file
-	for level, s := range nameMap {
-		if level >= splitLevel && level != ErrorLvl {
-			l := level
-			opts = append(opts, getOption(config, s, func(lvl LogLevel) bool {
-				return lvl >= GetLevel() && lvl == l
-			}))
+	var opts []option
+	seen := make(map[string]bool)
+	for lvl := DebugLvl; lvl <= FatalLvl; lvl++ {
+		name, write := route(lvl)
+		if !write || seen[name] {
+			continue
 		}
+		seen[name] = true
+		opts = append(opts, getOption(config, name, func(lvl LogLevel) bool {
+			if lvl < GetLevel() {
+				return false
+			}
+			n, w := route(lvl)
+			return w && n == name
+		}))
 	}
-	//error log contains all logs that loglevel > error
-	opts = append(opts, getOption(config, nameMap[ErrorLvl], func(lvl LogLevel) bool {
-		return lvl >= GetLevel() && lvl >= ErrorLvl
-	}))
 	return opts
 }
 
+// routeNameReserved - Return whether a RouteFunc can't write into the file name, empty or one of the other logs.
+func routeNameReserved(config *Config, name string) bool {
+	tracingLogFileName := config.TracingLogFileName
+	if tracingLogFileName == "" {
+		tracingLogFileName = DefaultTracingFileName
+	}
+	return name == "" || name == SysLogFileName || name == SysErrorLogFileName || name == AccessLogFileName ||
+		name == AuditLogFileName || name == tracingLogFileName
+}
+
+// splitFileName - Return the name of the file of the logs at lvl with splitLevel: server.log below splitLevel,
+// the file of their level from splitLevel up to warn, and error.log from error.
+func splitFileName(config *Config, splitLevel, lvl LogLevel) string {
+	switch {
+	case lvl >= ErrorLvl:
+		return nameMap[ErrorLvl]
+	case lvl >= splitLevel:
+		return nameMap[lvl]
+	default:
+		return config.LogFileName
+	}
+}
+
 func getOption(config *Config, fileName string, enablerFunc zap.LevelEnablerFunc) option {
 	return option{
 		Filename: env.GetFilePath(config.Path, fileName),
 		Ropt: rotateOptions{
-			MaxSize:    100,
-			MaxAge:     7,
-			MaxBackups: 10,
-			Compress:   config.Compress,
+			MaxSize:       100,
+			MaxAge:        7,
+			MaxBackups:    10,
+			Compress:      config.Compress,
+			Level:         config.CompressionLevel,
+			CopyTrunc:     config.RotationMode == RotationCopyTruncate,
+			FlushInterval: config.FlushInterval,
+			Interval:      config.RotateInterval,
 		},
-		Lef: enablerFunc,
+		Encoding:     config.Encoding,
+		JSONSeq:      config.JSONSeq,
+		LevelEnc:     config.LevelEncoder,
+		TimeEnc:      config.TimeEncoding,
+		TimeLayout:   config.TimeLayout,
+		UTC:          config.UseUTC,
+		NoStack:      config.DisableStacktrace,
+		StackLevel:   config.StacktraceLevel,
+		NoCaller:     config.DisableCaller,
+		AlsoStdout:   config.AlsoStdout,
+		RedactKeys:   config.DeepRedactKeys,
+		HumanDur:     config.HumanReadableDurations,
+		FieldPrefix:  config.FieldPrefix,
+		Separator:    config.ConsoleSeparator,
+		TraceField:   config.EmitTraceAsField,
+		ReflBytes:    config.MaxReflectedBytes,
+		ReflDepth:    config.MaxReflectedDepth,
+		FieldHints:   config.FieldHints,
+		MaxLine:      config.MaxLineBytes,
+		MaxMsg:       config.MaxMessageBytes,
+		MaxField:     config.MaxFieldBytes,
+		SyslogAddr:   config.SyslogAddr,
+		Journald:     config.UseJournald,
+		KafkaBrokers: config.KafkaBrokers,
+		KafkaTopic:   config.KafkaTopic,
+		QueuePolicy:  config.QueueFullPolicy,
+		Async:        config.Async,
+		AsyncSize:    config.AsyncQueueSize,
+		Lef:          enablerFunc,
+	}
+}
+
+func getStdoutOption(config *Config, enablerFunc zap.LevelEnablerFunc) option {
+	return option{
+		Stdout:      true,
+		Encoding:    config.Encoding,
+		JSONSeq:     config.JSONSeq,
+		LevelEnc:    config.LevelEncoder,
+		TimeEnc:     config.TimeEncoding,
+		TimeLayout:  config.TimeLayout,
+		UTC:         config.UseUTC,
+		NoStack:     config.DisableStacktrace,
+		StackLevel:  config.StacktraceLevel,
+		NoCaller:    config.DisableCaller,
+		RedactKeys:  config.DeepRedactKeys,
+		HumanDur:    config.HumanReadableDurations,
+		FieldPrefix: config.FieldPrefix,
+		Separator:   config.ConsoleSeparator,
+		TraceField:  config.EmitTraceAsField,
+		ReflBytes:   config.MaxReflectedBytes,
+		ReflDepth:   config.MaxReflectedDepth,
+		FieldHints:  config.FieldHints,
+		MaxLine:     config.MaxLineBytes,
+		MaxMsg:      config.MaxMessageBytes,
+		MaxField:    config.MaxFieldBytes,
+		Lef:         enablerFunc,
 	}
 }
 
@@ -316,115 +852,271 @@ func getDefaultOpt(config *Config) []option {
 }
 
 type rotateOptions struct {
-	MaxSize    int
-	MaxAge     int
-	MaxBackups int
-	Compress   bool
+	MaxSize       int
+	MaxAge        int
+	MaxBackups    int
+	Compress      bool
+	Level         int
+	CopyTrunc     bool
+	FlushInterval time.Duration
+	Unbuffered    bool
+	Interval      time.Duration
 }
 
 type option struct {
-	LocalTime bool
-	Stdout    bool
-	Filename  string
-	Ropt      rotateOptions
-	Lef       zap.LevelEnablerFunc
+	LocalTime    bool
+	Stdout       bool
+	AlsoStdout   bool
+	TraceFirst   bool
+	Filename     string
+	Encoding     Encoding
+	JSONSeq      bool
+	LevelEnc     LevelEncoder
+	TimeEnc      TimeEncoding
+	TimeLayout   string
+	UTC          bool
+	NoStack      bool
+	StackLevel   *LogLevel
+	NoCaller     bool
+	RedactKeys   []string
+	HumanDur     bool
+	FieldPrefix  string
+	Separator    string
+	TraceField   bool
+	ReflBytes    int
+	ReflDepth    int
+	FieldHints   bool
+	MaxLine      int
+	MaxMsg       int
+	MaxField     int
+	SyslogAddr   string
+	Journald     bool
+	KafkaBrokers []string
+	KafkaTopic   string
+	QueuePolicy  QueueFullPolicy
+	Async        bool
+	AsyncSize    int
+	Ropt         rotateOptions
+	Lef          zap.LevelEnablerFunc
+	Clock        zapcore.Clock
+	// Untracked files are not closed by CloseAll nor counted by WriterStats, for NewLogger.
+	Untracked bool
 }
 
 func newLogger(opts ...option) *zap.Logger {
 	var cores []zapcore.Core
-	encCfg := extension.NewProductionEncoderConfig()
-	encCfg.EncodeTime = zapcore.TimeEncoderOfLayout(customTimeLayout)
-	encCfg.EncodeDuration = zapcore.MillisDurationEncoder
-	encCfg.ConsoleSeparator = "|"
-	encoder := extension.NewConsoleEncoder(encCfg)
-
+	noStack, noCaller := false, false
+	stackLevel := zap.PanicLevel
+	var clock zapcore.Clock
 	for _, opt := range opts {
-		core := newCore(encoder, opt)
+		core := newCore(newEncoder(opt), opt)
 		cores = append(cores, core)
+		noStack = noStack || opt.NoStack
+		noCaller = noCaller || opt.NoCaller
+		if opt.StackLevel != nil {
+			stackLevel = *opt.StackLevel
+		}
+		if opt.Clock != nil {
+			clock = opt.Clock
+		}
 	}
 
-	logger := zap.New(zapcore.NewTee(cores...), zap.AddCaller(), zap.AddStacktrace(zap.PanicLevel))
+	zapOpts := []zap.Option{countLevel}
+	if clock != nil {
+		zapOpts = append(zapOpts, zap.WithClock(clock))
+	}
+	if !noCaller {
+		zapOpts = append(zapOpts, zap.AddCaller())
+	}
+	if !noStack {
+		zapOpts = append(zapOpts, zap.AddStacktrace(stackLevel))
+	}
+	logger := zap.New(zapcore.NewTee(cores...), zapOpts...)
 	logger = logger.With(zap.String(TraceKey, "-"))
 
 	return logger
 }
 
+func newEncoder(opt option) zapcore.Encoder {
+	cfg := extension.NewProductionEncoderConfig()
+	cfg.EncodeTime = timeEncoder(opt.TimeEnc, opt.TimeLayout)
+	if opt.UTC {
+		encodeTime := cfg.EncodeTime
+		cfg.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			encodeTime(t.UTC(), enc)
+		}
+	}
+	cfg.EncodeDuration = zapcore.MillisDurationEncoder
+	// the JSON logs keep numbers for the machines
+	if opt.HumanDur && opt.Encoding != EncodingJSON {
+		cfg.EncodeDuration = zapcore.StringDurationEncoder
+	}
+	cfg.ConsoleSeparator = "|"
+	if opt.Separator != "" {
+		cfg.ConsoleSeparator = opt.Separator
+	}
+	cfg.TraceFirst = opt.TraceFirst
+	cfg.EncodeLevel = levelEncoder(opt.LevelEnc)
+	cfg.FieldHints = opt.FieldHints
+	cfg.RedactKeys = opt.RedactKeys
+	cfg.FieldPrefix = opt.FieldPrefix
+	cfg.EmitTraceAsField = opt.TraceField
+	cfg.MaxReflectedBytes = opt.ReflBytes
+	cfg.MaxReflectedDepth = opt.ReflDepth
+	cfg.MaxMessageBytes = opt.MaxMsg
+	cfg.MaxFieldBytes = opt.MaxField
+	cfg.JSONSeq = opt.JSONSeq
+	if opt.NoStack {
+		cfg.StacktraceKey = ""
+	}
+	enc := extension.NewConsoleEncoder(cfg)
+	if opt.Encoding == EncodingJSON {
+		enc = extension.NewJSONEncoder(cfg)
+	}
+	if opt.MaxLine > 0 {
+		return newTruncatingEncoder(enc, opt.MaxLine)
+	}
+	return enc
+}
+
+// levelEncoder - Return the zap level encoder of e, lowercase by default.
+func levelEncoder(e LevelEncoder) zapcore.LevelEncoder {
+	switch e {
+	case LevelEncoderCapital:
+		return zapcore.CapitalLevelEncoder
+	case LevelEncoderNumeric:
+		return extension.NumericLevelEncoder
+	default:
+		return zapcore.LowercaseLevelEncoder
+	}
+}
+
+// timeEncoder - Return the zap time encoder of e, writing times with layout (customTimeLayout if empty) by default.
+func timeEncoder(e TimeEncoding, layout string) zapcore.TimeEncoder {
+	switch e {
+	case TimeEncodingEpoch:
+		return epochTimeEncoder
+	case TimeEncodingEpochMillis:
+		return epochMillisTimeEncoder
+	case TimeEncodingISO8601:
+		return zapcore.ISO8601TimeEncoder
+	default:
+		if layout == "" {
+			layout = customTimeLayout
+		}
+		return zapcore.TimeEncoderOfLayout(layout)
+	}
+}
+
+// epochTimeEncoder - Like zapcore.EpochTimeEncoder, without the exponent the console encoder would print.
+func epochTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', -1, 64))
+}
+
+func epochMillisTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(t.UnixNano() / int64(time.Millisecond))
+}
+
 func newCore(encoder zapcore.Encoder, opt option) zapcore.Core {
 	lv := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 		return opt.Lef(lvl)
 	})
 
+	if core := newJournaldCore(encoder, opt, lv); core != nil {
+		return withAsync(withStdout(core, encoder, opt, lv), opt)
+	}
+	if core := newSyslogCore(encoder, opt, lv); core != nil {
+		return withAsync(withStdout(core, encoder, opt, lv), opt)
+	}
+
 	var syncer io.Writer
 	if opt.Stdout {
 		syncer = os.Stdout
+	} else if w := newKafkaWriter(opt); w != nil {
+		syncer = w
 	} else {
-		syncer = &lumberjack.Logger{
-			LocalTime:  opt.LocalTime,
-			Filename:   opt.Filename,
-			MaxSize:    opt.Ropt.MaxSize,
-			MaxBackups: opt.Ropt.MaxBackups,
-			MaxAge:     opt.Ropt.MaxAge,
-			Compress:   opt.Ropt.Compress,
-		}
+		syncer = newFileWriter(opt)
 	}
 	w := zapcore.AddSync(syncer)
+	if opt.AlsoStdout && !opt.Stdout {
+		w = zapcore.NewMultiWriteSyncer(w, zapcore.AddSync(os.Stdout))
+	}
 	core := zapcore.NewCore(
 		encoder,
 		zapcore.AddSync(w),
 		lv,
 	)
-	return core
+	// the Kafka writer queues the logs already
+	if _, queued := syncer.(queueWriter); queued {
+		return core
+	}
+	return withAsync(core, opt)
 }
 
-func defaultLevel() zapcore.Level {
-	if env.IsLive() {
-		return zap.InfoLevel
+// newFileWriter - Return the rotated log file of opt, it's opened on the first write.
+func newFileWriter(opt option) *lumberjack.Logger {
+	lj := &lumberjack.Logger{
+		LocalTime:        opt.LocalTime && !opt.UTC,
+		Filename:         opt.Filename,
+		MaxSize:          opt.Ropt.MaxSize,
+		MaxBackups:       opt.Ropt.MaxBackups,
+		MaxAge:           opt.Ropt.MaxAge,
+		Compress:         opt.Ropt.Compress,
+		CompressionLevel: opt.Ropt.Level,
+		CopyTruncate:     opt.Ropt.CopyTrunc,
+		FlushInterval:    opt.Ropt.FlushInterval,
+		Unbuffered:       opt.Ropt.Unbuffered,
+		RotateInterval:   opt.Ropt.Interval,
 	}
-	return zap.DebugLevel
+	if !opt.Untracked {
+		registerFileWriter(lj)
+	}
+	return lj
 }
 
-// SetLevel - Dynamically set the log level. Time duration only works when
-// setting log level to debug on live environment, that is, when the log level
-// is dynamically set to debug level in live env, it will be reset
-// to the initial log level configuration (default InfoLvl if not specified initially)
-// after the time duration.
-func SetLevel(level zapcore.Level, duration time.Duration) {
-	if env.IsLive() && level < zap.InfoLevel {
-		if duration > maxResetLvlDur {
-			duration = maxResetLvlDur
-		}
-
-		ver := resetVer.Add(1)
-		if ver > maxResetVer {
-			ver = ver % maxResetVer
-			resetVer.Store(ver)
-		}
-
-		time.AfterFunc(duration, func() {
-			resetLevel(ver)
-		})
+// withStdout - Return core printing its logs into stdout too if opt is AlsoStdout.
+func withStdout(core zapcore.Core, encoder zapcore.Encoder, opt option, lv zapcore.LevelEnabler) zapcore.Core {
+	if !opt.AlsoStdout {
+		return core
 	}
+	return zapcore.NewTee(core, zapcore.NewCore(encoder.Clone(), zapcore.AddSync(os.Stdout), lv))
+}
 
-	logLevel.Store(int32(level))
+// printStderr - Print the message into stderr, after the current time, e.g. when a sink can't be reached.
+func printStderr(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, time.Now().Format(customTimeLayout)+" "+format, args...)
 }
 
-func resetLevel(ver int64) {
-	if GetLevel() < InfoLvl && resetVer.Load() == ver {
-		SetLevel(initialLogLevel, 0)
-		return
+func defaultLevel() zapcore.Level {
+	if env.IsLive() {
+		return zap.InfoLevel
 	}
+	return zap.DebugLevel
+}
+
+// SetLevel - Dynamically set the log level of the user, system and tracing loggers.
+// Time duration only works when setting log level to debug, that is,
+// when the log level is dynamically set to debug level with a duration, it will be reset
+// to the level set before (the initial log level configuration if not changed since)
+// after the time duration, in any environment. In live env, the level can't go below Config.MinLevelInLive
+// (info by default) without a duration, to prevent an accidental permanent debug level. Use SetUserLevel, SetSysLevel or SetTracingLevel to set one logger only.
+func SetLevel(level zapcore.Level, duration time.Duration) {
+	userLevel.SetLevel(level, duration)
+	sysLevel.SetLevel(level, duration)
+	tracingLevel.SetLevel(level, duration)
 }
 
+// GetLevel - Return the level of the user logger.
 func GetLevel() zapcore.Level {
-	return zapcore.Level(int8(logLevel.Load()))
+	return userLevel.Level()
 }
 
 // SetLogFileName Set the filename of logs that split by level.
 // Return true if newName is valid and set success,return false if newName is duplicate with other log file and set fail.
-// Should be called before logger initialization.
+// Must be called before logger initialization, as the log files are already opened after it: return false after it.
 // E.g. SetLogFileName(log.DebugLvl,"my_debug") then all the debug log will write into my_debug.log.
 func SetLogFileName(level LogLevel, newName string) bool {
-	if !checkLogFileNameValid(level, newName) {
+	if loggerInitialized.Load() || !checkLogFileNameValid(level, newName) {
 		return false
 	}
 	nameMap[level] = newName
@@ -432,7 +1124,7 @@ func SetLogFileName(level LogLevel, newName string) bool {
 }
 
 func checkLogFileNameValid(level LogLevel, newName string) bool {
-	if newName == "" || newName == SysLogFileName || newName == SysErrorLogFileName || newName == DefaultLogFileName || newName == DefaultTracingFileName {
+	if newName == "" || newName == SysLogFileName || newName == SysErrorLogFileName || newName == DefaultLogFileName || newName == DefaultTracingFileName || newName == AccessLogFileName || newName == AuditLogFileName {
 		return false
 	}
 	for l, s := range nameMap {
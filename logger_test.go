@@ -0,0 +1,824 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/caser789/logger/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newTestLogger returns a logger writing into a file under a temp dir,
+// and a function to flush the logger and read back what has been written.
+func newTestLogger(t *testing.T, config *Config) (*zap.Logger, func() string) {
+	config.Path = t.TempDir()
+	opt := getOption(config, "test", func(lvl LogLevel) bool {
+		return lvl >= GetLevel()
+	})
+	l := newLogger(opt).With(configFields(config)...)
+	return l, func() string {
+		_ = l.Sync()
+		data, err := os.ReadFile(filepath.Join(config.Path, "test.log"))
+		assert.Nil(t, err)
+		return string(data)
+	}
+}
+
+// sinksOf - Return the open sinks replacing the log file name.
+func sinksOf(name string) []io.Closer {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	var sinks []io.Closer
+	for w, file := range openSinks {
+		if file == name {
+			sinks = append(sinks, w)
+		}
+	}
+	return sinks
+}
+
+func TestSplitRouting(t *testing.T) {
+	defer SetLevel(GetLevel(), 0)
+	SetLevel(DebugLvl, 0)
+
+	levels := []LogLevel{DebugLvl, InfoLvl, WarnLvl, ErrorLvl, DPanicLvl}
+	tests := []struct {
+		split SplitLevel
+		files map[string][]LogLevel
+	}{
+		{SplitDebug, map[string][]LogLevel{
+			"debug": {DebugLvl}, "info": {InfoLvl}, "warn": {WarnLvl}, "error": {ErrorLvl, DPanicLvl},
+		}},
+		{SplitInfo, map[string][]LogLevel{
+			"server": {DebugLvl}, "info": {InfoLvl}, "warn": {WarnLvl}, "error": {ErrorLvl, DPanicLvl},
+		}},
+		{SplitWarn, map[string][]LogLevel{
+			"server": {DebugLvl, InfoLvl}, "warn": {WarnLvl}, "error": {ErrorLvl, DPanicLvl},
+		}},
+		{SplitError, map[string][]LogLevel{
+			"server": {DebugLvl, InfoLvl, WarnLvl}, "error": {ErrorLvl, DPanicLvl},
+		}},
+	}
+	for _, tt := range tests {
+		config := &Config{Path: t.TempDir(), LogFileName: "server"}
+		splitLevel, ok := checkLevel(tt.split)
+		assert.True(t, ok)
+		l := newLogger(getSplitOpt(config, splitLevel)...)
+		for _, lvl := range levels {
+			l.Check(lvl, "line at "+lvl.String()).Write()
+		}
+		assert.Nil(t, l.Sync())
+
+		entries, err := os.ReadDir(config.Path)
+		assert.Nil(t, err)
+		assert.Len(t, entries, len(tt.files), string(tt.split))
+		for name, want := range tt.files {
+			data, err := os.ReadFile(filepath.Join(config.Path, name+".log"))
+			assert.Nil(t, err, name)
+			var got []LogLevel
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				var lvl LogLevel
+				assert.Nil(t, lvl.UnmarshalText([]byte(line[strings.Index(line, "line at ")+len("line at "):])))
+				got = append(got, lvl)
+			}
+			assert.Equal(t, want, got, "%s: %s.log", tt.split, name)
+		}
+	}
+}
+
+func TestSplitRoutingLevelChange(t *testing.T) {
+	defer SetLevel(GetLevel(), 0)
+
+	levels := []LogLevel{DebugLvl, InfoLvl, WarnLvl, ErrorLvl, DPanicLvl}
+	for _, split := range []SplitLevel{SplitDebug, SplitInfo, SplitWarn, SplitError} {
+		config := &Config{Path: t.TempDir(), LogFileName: "server"}
+		splitLevel, ok := checkLevel(split)
+		assert.True(t, ok)
+		l := newLogger(getSplitOpt(config, splitLevel)...)
+		// raise the level above each split boundary at runtime, logging every level at each step
+		want := map[string]int{}
+		for _, current := range levels {
+			SetLevel(current, 0)
+			for _, lvl := range levels {
+				msg := fmt.Sprintf("line at %s with level %s", lvl, current)
+				l.Check(lvl, msg).Write()
+				if lvl >= current {
+					want[msg]++
+				}
+			}
+		}
+		assert.Nil(t, l.Sync())
+
+		entries, err := os.ReadDir(config.Path)
+		assert.Nil(t, err)
+		got := map[string]int{}
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join(config.Path, entry.Name()))
+			assert.Nil(t, err)
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				got[line[strings.Index(line, "line at "):]]++
+			}
+		}
+		assert.Equal(t, want, got, string(split))
+	}
+}
+
+func TestRouteFunc(t *testing.T) {
+	defer SetLevel(GetLevel(), 0)
+	SetLevel(DebugLvl, 0)
+
+	config := &Config{Path: t.TempDir(), SplitLevel: SplitDebug, RouteFunc: func(lvl LogLevel) (string, bool) {
+		switch {
+		case lvl == DebugLvl:
+			return "", false
+		case lvl >= WarnLvl:
+			return "problems", true
+		default:
+			return "app", true
+		}
+	}}
+	l := newLogger(userLoggerOptions(config)...)
+	for _, lvl := range []LogLevel{DebugLvl, InfoLvl, WarnLvl, ErrorLvl, DPanicLvl} {
+		l.Check(lvl, "line at "+lvl.String()).Write()
+	}
+	assert.Nil(t, l.Sync())
+
+	entries, err := os.ReadDir(config.Path)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	for name, want := range map[string][]string{
+		"app":      {"line at info"},
+		"problems": {"line at warn", "line at error", "line at dpanic"},
+	} {
+		data, err := os.ReadFile(filepath.Join(config.Path, name+".log"))
+		assert.Nil(t, err, name)
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			got = append(got, line[strings.Index(line, "line at "):])
+		}
+		assert.Equal(t, want, got, name)
+	}
+}
+
+func TestRouteFuncReservedNames(t *testing.T) {
+	defer SetLevel(GetLevel(), 0)
+	SetLevel(DebugLvl, 0)
+
+	config := &Config{Path: t.TempDir(), RouteFunc: func(lvl LogLevel) (string, bool) {
+		if lvl >= WarnLvl {
+			return SysLogFileName, true
+		}
+		return "app", true
+	}}
+	l := newLogger(userLoggerOptions(config)...)
+	l.Info("to app")
+	l.Warn("to sys")
+	assert.Nil(t, l.Sync())
+
+	entries, err := os.ReadDir(config.Path)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	data, err := os.ReadFile(filepath.Join(config.Path, DefaultLogFileName+".log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "|to sys\n")
+}
+
+func TestRouteFuncDroppingAllLevels(t *testing.T) {
+	oldInitialized, oldLogger := loggerInitialized.Load(), logger.Load()
+	defer func() {
+		loggerInitialized.Store(oldInitialized)
+		logger.Store(oldLogger)
+	}()
+
+	tmp := t.TempDir()
+	config := &Config{Path: tmp, RouteFunc: func(LogLevel) (string, bool) { return "", false }}
+	assert.NotPanics(t, func() { initDefaultLogger(config) })
+
+	GetLogger().Error("dropped")
+	entries, err := os.ReadDir(tmp)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 0)
+}
+
+func TestWithRegion(t *testing.T) {
+	os.Setenv("REGION", "sg")
+	defer os.Unsetenv("REGION")
+
+	l, read := newTestLogger(t, &Config{WithRegion: true})
+	l.Info("with region")
+	assert.Contains(t, read(), `"region":"sg"`)
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("without region")
+	assert.NotContains(t, read(), `"region"`)
+}
+
+func TestWithRegionFromDatacenter(t *testing.T) {
+	os.Unsetenv("REGION")
+	os.Setenv("DATACENTER", "us-east")
+	defer os.Unsetenv("DATACENTER")
+
+	l, read := newTestLogger(t, &Config{WithRegion: true})
+	l.Info("with datacenter")
+	assert.Contains(t, read(), `"region":"us-east"`)
+}
+
+func TestTracingLoggerTraceFirst(t *testing.T) {
+	oldTracingLogger := tracingLogger.Load()
+	t.Cleanup(func() { tracingLogger.Store(oldTracingLogger) })
+	config := &Config{Path: t.TempDir(), TraceFirst: true}
+	initTracingLogger(config)
+	tracingLogger.Load().With(zap.String(TraceKey, "trace-id")).Info("tracing")
+	_ = tracingLogger.Load().Sync()
+
+	data, err := os.ReadFile(filepath.Join(config.Path, DefaultTracingFileName+".log"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "trace-id|"), string(data))
+}
+
+func TestAccessLog(t *testing.T) {
+	config := &Config{Path: t.TempDir()}
+	accessLoggerInitOnce.Do(func() {})
+	initAccessLogger(config)
+	ctx, _ := WithNewTraceLog("access", context.Background())
+	AccessLog(ctx, AccessEntry{
+		Method:  "GET",
+		Path:    "/api/v1/ping",
+		Status:  200,
+		Bytes:   512,
+		Latency: 15 * time.Millisecond,
+	})
+	_ = GetAccessLogger().Sync()
+
+	data, err := os.ReadFile(filepath.Join(config.Path, AccessLogFileName+".log"))
+	assert.Nil(t, err)
+	line := string(data)
+	assert.Contains(t, line, GetTraceIDFromCtx(ctx))
+	assert.Contains(t, line, `"method":"GET"`)
+	assert.Contains(t, line, `"path":"/api/v1/ping"`)
+	assert.Contains(t, line, `"status":200`)
+	assert.Contains(t, line, `"bytes":512`)
+	assert.Contains(t, line, `"latency":15`)
+}
+
+func TestWriterStats(t *testing.T) {
+	config := &Config{}
+	l, read := newTestLogger(t, config)
+	l.Info("stats")
+	read()
+
+	stats, ok := WriterStats()[filepath.Join(config.Path, "test.log")]
+	assert.True(t, ok)
+	assert.True(t, stats.BytesWritten > 0)
+	assert.True(t, stats.Flushes > 0)
+}
+
+func TestIsInternalRequest(t *testing.T) {
+	sc := trace.NewSpanContextGenerator("").NewSpanContext(trace.IsInternal(true))
+	assert.True(t, IsInternalRequest(WithSpanContext(context.Background(), sc)))
+
+	sc = trace.NewSpanContextGenerator("").NewSpanContext()
+	assert.False(t, IsInternalRequest(WithSpanContext(context.Background(), sc)))
+	assert.False(t, IsInternalRequest(context.Background()))
+}
+
+func TestIsEntrySpan(t *testing.T) {
+	sc := trace.NewSpanContextGenerator("").NewSpanContext()
+	assert.False(t, IsEntrySpan(WithSpanContext(context.Background(), sc)))
+	assert.True(t, IsEntrySpan(WithSpanContext(context.Background(), sc.NewChildSpanContext())))
+	assert.False(t, IsEntrySpan(context.Background()))
+}
+
+func TestSyslogAddr(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	config := &Config{Path: t.TempDir(), SyslogAddr: "udp://" + conn.LocalAddr().String()}
+	l := newLogger(getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	}))
+	l.Info("to syslog")
+
+	buf := make([]byte, 4096)
+	assert.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, "<14>"), msg)
+	assert.Contains(t, msg, " server[")
+	assert.Contains(t, msg, "|info|")
+	assert.Contains(t, msg, "|to syslog")
+
+	_, err = os.Stat(filepath.Join(config.Path, "server.log"))
+	assert.True(t, os.IsNotExist(err))
+
+	// facility user, with the severity of the level
+	for lvl, priority := range map[LogLevel]string{WarnLvl: "<12>", ErrorLvl: "<11>", DPanicLvl: "<10>"} {
+		l.Check(lvl, "to syslog").Write()
+		n, _, err = conn.ReadFrom(buf)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(buf[:n]), priority), string(buf[:n]))
+	}
+
+	// the connection is closed with the logger
+	opt := getOption(config, "server", nil)
+	sinks := sinksOf(opt.Filename)
+	assert.Len(t, sinks, 1)
+	assert.Nil(t, closeSinks(sinks))
+	assert.Empty(t, sinksOf(opt.Filename))
+}
+
+func TestPrintStderr(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+	oldStderr := os.Stderr
+	os.Stderr = w
+	printStderr("can't connect to %s\n", "sink")
+	os.Stderr = oldStderr
+	assert.Nil(t, w.Close())
+
+	data, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}[.\d]*[+-]\d{2}:\d{2} can't connect to sink\n$`, string(data))
+	assert.NotContains(t, string(data), "m=+")
+}
+
+func TestSyslogAddrFallback(t *testing.T) {
+	// nothing listens on this port
+	config := &Config{Path: t.TempDir(), SyslogAddr: "tcp://127.0.0.1:1"}
+	l := newLogger(getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	}))
+	l.Info("to file")
+	assert.Nil(t, l.Sync())
+
+	data, err := os.ReadFile(filepath.Join(config.Path, "server.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "to file")
+}
+
+func TestSyslogAddrWriteFallback(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	config := &Config{Path: t.TempDir(), SyslogAddr: "tcp://" + ln.Addr().String()}
+	l := newLogger(getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	}))
+	defer closeSinks(sinksOf(getOption(config, "server", nil).Filename))
+	l.Info("to syslog")
+
+	conn := <-accepted
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Contains(t, line, "|to syslog")
+
+	// syslog goes away mid-stream, the writes fail once the peer resets the connection
+	assert.Nil(t, ln.Close())
+	assert.Nil(t, conn.Close())
+	for i := 0; i < 5; i++ {
+		l.Info("to file", zap.Int("i", i))
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, l.Sync())
+
+	data, err := os.ReadFile(filepath.Join(config.Path, "server.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `|to file|{"i":4}`)
+	assert.NotContains(t, string(data), "to syslog")
+}
+
+func TestLogWithSeq(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	ctx := WithLogger(context.Background(), l)
+
+	LogWithSeq(ctx, 42, WarnLvl, "with seq", zap.String("k", "v"))
+	LogWithSeq(ctx, 43, DebugLvl, "filtered")
+
+	out := read()
+	assert.Contains(t, out, "|warn|")
+	assert.Contains(t, out, `"seq":42`)
+	assert.Contains(t, out, `"k":"v"`)
+	assert.NotContains(t, out, "filtered")
+}
+
+func TestNumericLevelEncoder(t *testing.T) {
+	l, read := newTestLogger(t, &Config{LevelEncoder: LevelEncoderNumeric})
+	l.Warn("numeric level")
+	assert.Contains(t, read(), "|30|")
+}
+
+func TestSetLogFileNameAfterInit(t *testing.T) {
+	oldInitialized, oldNames := loggerInitialized.Load(), nameMap[DebugLvl]
+	oldLogger := logger.Load()
+	defer func() {
+		loggerInitialized.Store(oldInitialized)
+		nameMap[DebugLvl] = oldNames
+		logger.Store(oldLogger)
+	}()
+
+	loggerInitialized.Store(false)
+	assert.True(t, SetLogFileName(DebugLvl, "my_debug"))
+
+	initDefaultLogger(&Config{Path: t.TempDir(), SplitLevel: SplitDebug})
+	assert.False(t, SetLogFileName(DebugLvl, "other_debug"))
+	assert.Equal(t, "my_debug", nameMap[DebugLvl])
+}
+
+func TestDisableStacktrace(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	assert.Panics(t, func() { l.Panic("with stack") })
+	assert.Contains(t, read(), "testing.tRunner")
+
+	defer stacktraceDisabled.Store(stacktraceDisabled.Load())
+	stacktraceDisabled.Store(true)
+	l, read = newTestLogger(t, &Config{DisableStacktrace: true})
+	assert.Panics(t, func() { l.Panic("without stack") })
+	err := &stackError{msg: "failed", stack: testStack{"main.main"}}
+	l.Error("error", ErrorField(err))
+	out := read()
+	assert.Contains(t, out, "without stack")
+	assert.NotContains(t, out, "testing.tRunner")
+	assert.NotContains(t, out, "errorStack")
+}
+
+func TestWithFields(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	ctx := WithLogger(context.Background(), l)
+	ctx = WithFields(ctx, zap.String("user_id", "u1"))
+	ctx = WithFields(ctx, zap.String("request_id", "r1"))
+
+	Info(ctx, "enriched", zap.Int("n", 1))
+	Info(WithLogger(context.Background(), l), "plain")
+	out := read()
+	assert.Contains(t, out, `"user_id":"u1","request_id":"r1","n":1`)
+	assert.True(t, strings.HasSuffix(out, "|plain\n"), out)
+}
+
+func TestFieldHints(t *testing.T) {
+	l, read := newTestLogger(t, &Config{FieldHints: true})
+	l = l.With(IndexedField(zap.String("user_id", "u1")))
+	l.Info("hinted", zap.Int("n", 1), StoredField(zap.String("body", "b")), IndexedField(zap.String("order_id", "o1")))
+	assert.Contains(t, read(), `{"n":1,"indexed":{"user_id":"u1","order_id":"o1"},"stored":{"body":"b"}}`)
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("plain", zap.Int("n", 1), StoredField(zap.String("body", "b")), IndexedField(zap.String("order_id", "o1")))
+	assert.Contains(t, read(), `{"n":1,"body":"b","order_id":"o1"}`)
+}
+
+func TestMaxLineBytes(t *testing.T) {
+	l, read := newTestLogger(t, &Config{MaxLineBytes: 200})
+	l.Info("short")
+	l.Info(strings.Repeat("x", 1000), zap.String("k", "v"))
+	l.Info(strings.Repeat("é", 500))
+
+	lines := strings.SplitAfter(read(), "\n")
+	assert.Equal(t, "", lines[3])
+	assert.True(t, strings.HasSuffix(lines[0], "|short\n"), lines[0])
+	for _, line := range lines[1:3] {
+		assert.True(t, len(line) <= 200, len(line))
+		assert.True(t, len(line) >= 190, len(line))
+		assert.True(t, strings.HasSuffix(line, "x...(truncated)\n") || strings.HasSuffix(line, "é...(truncated)\n"), line)
+	}
+}
+
+func TestJSONSeq(t *testing.T) {
+	l, read := newTestLogger(t, &Config{Encoding: EncodingJSON, JSONSeq: true})
+	l.Info("first", zap.Int("n", 1))
+	l.Warn("multi\nline")
+
+	records := strings.Split(read(), "\x1e")
+	assert.Len(t, records, 3)
+	assert.Equal(t, "", records[0])
+	for _, record := range records[1:] {
+		assert.True(t, strings.HasSuffix(record, "}\n"), record)
+		var decoded map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(record), &decoded), record)
+		assert.Equal(t, "-", decoded[TraceKey])
+	}
+	assert.Contains(t, records[1], `"level":"info"`)
+	assert.Contains(t, records[1], `"msg":"first","n":1}`)
+	assert.Contains(t, records[2], `"msg":"multi\nline"}`)
+
+	// JSON without the framing, and the console encoding ignoring it
+	l, read = newTestLogger(t, &Config{Encoding: EncodingJSON})
+	l.Info("plain")
+	assert.True(t, strings.HasPrefix(read(), `{"ts":"`))
+	l, read = newTestLogger(t, &Config{JSONSeq: true})
+	l.Info("console")
+	line := read()
+	assert.NotContains(t, line, "\x1e")
+	assert.True(t, strings.HasSuffix(line, "|-|console\n"), line)
+}
+
+func TestMaxMessageBytes(t *testing.T) {
+	l, read := newTestLogger(t, &Config{MaxMessageBytes: 100})
+	l.Info(strings.Repeat("x", 2<<20), zap.String("k", "v"))
+	l.Info(strings.Repeat("x", 99) + "é")
+
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], "|"+strings.Repeat("x", 100)+`...(truncated)|{"k":"v"}`), lines[0])
+	// "é" would end at the 101st byte
+	assert.True(t, strings.HasSuffix(lines[1], "|"+strings.Repeat("x", 99)+"...(truncated)"), lines[1])
+	assert.True(t, utf8.ValidString(lines[1]))
+}
+
+func TestMaxFieldBytes(t *testing.T) {
+	l, read := newTestLogger(t, &Config{MaxFieldBytes: 16})
+	l.Info("huge field", zap.String("body", strings.Repeat("x", 1<<20)))
+	line := read()
+	assert.Contains(t, line, `|huge field|{"body":"xxxxxxxxxxxxxxxx...(truncated)"}`)
+	assert.True(t, len(line) < 200, len(line))
+}
+
+func TestWithRunID(t *testing.T) {
+	l, read := newTestLogger(t, &Config{WithRunID: true})
+	l.Info("first")
+	l.With(zap.String("k", "v")).Info("second")
+	sys, readSys := newTestLogger(t, &Config{WithRunID: true})
+	sys.Info("other logger")
+
+	runIDField := `"run_id":"` + getRunID() + `"`
+	assert.Len(t, getRunID(), 16)
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Contains(t, line, runIDField)
+	}
+	assert.Contains(t, readSys(), runIDField)
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("without run id")
+	assert.NotContains(t, read(), "run_id")
+}
+
+func TestIncludeHostPID(t *testing.T) {
+	l, read := newTestLogger(t, &Config{IncludeHostPID: true})
+	l.With(zap.String("k", "v")).Info("with host and pid")
+	out := read()
+	name, err := os.Hostname()
+	if err != nil {
+		name = "unknown"
+	}
+	assert.Contains(t, out, `"host":"`+name+`"`)
+	assert.Contains(t, out, fmt.Sprintf(`"pid":%d`, os.Getpid()))
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("without host and pid")
+	assert.NotContains(t, read(), `"pid"`)
+}
+
+func TestDisableCaller(t *testing.T) {
+	l, read := newTestLogger(t, &Config{DisableCaller: true})
+	l.Info("without caller")
+	out := read()
+	assert.NotContains(t, out, "logger_test.go")
+	assert.Contains(t, out, "|info|-|without caller")
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("with caller")
+	assert.Contains(t, read(), "/logger_test.go:")
+}
+
+func TestStacktraceLevel(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	l.Error("default error")
+	assert.NotContains(t, read(), "testing.tRunner")
+
+	warn := WarnLvl
+	l, read = newTestLogger(t, &Config{StacktraceLevel: &warn})
+	l.Info("info without stack")
+	assert.NotContains(t, read(), "testing.tRunner")
+	l.Warn("warn with stack")
+	out := read()
+	assert.Contains(t, out, "|warn with stack\n")
+	assert.Contains(t, out, "testing.tRunner")
+
+	info := InfoLvl
+	l, read = newTestLogger(t, &Config{StacktraceLevel: &info})
+	l.Info("info with stack")
+	out = read()
+	assert.Contains(t, out, "|info with stack\n")
+	assert.Contains(t, out, "testing.tRunner")
+
+	config, err := LoadConfig(strings.NewReader("stacktraceLevel: info"))
+	assert.Nil(t, err)
+	assert.Equal(t, &info, config.StacktraceLevel)
+}
+
+type nested struct {
+	Child *nested `json:"child,omitempty"`
+	Text  string  `json:"text,omitempty"`
+}
+
+func TestMaxReflected(t *testing.T) {
+	deep := &nested{}
+	for i := 0; i < 5; i++ {
+		deep = &nested{Child: deep}
+	}
+	cyclic := &nested{}
+	cyclic.Child = cyclic
+
+	l, read := newTestLogger(t, &Config{MaxReflectedBytes: 64, MaxReflectedDepth: 3})
+	l.Info("reflected", zap.Any("deep", deep), zap.Any("large", nested{Text: strings.Repeat("x", 100)}),
+		zap.Any("cyclic", cyclic), zap.Any("small", map[string]int{"a": 1}))
+	assert.Contains(t, read(), `{"deep":"...(too deep)","large":"...(too large)","cyclic":"...(too deep)","small":{"a":1}}`)
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("reflected", zap.Any("deep", deep))
+	assert.Contains(t, read(), `{"deep":{"child":{"child":{"child":{"child":{"child":{}}}}}}}`)
+}
+
+func TestAlsoStdout(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	l, read := newTestLogger(t, &Config{AlsoStdout: true})
+	os.Stdout = stdout
+
+	l.Info("to both")
+	file := read()
+	assert.Nil(t, w.Close())
+	printed, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Contains(t, file, "|to both\n")
+	assert.Equal(t, file, string(printed))
+}
+
+type testCredentials struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+type testRequest struct {
+	Path   string            `json:"path"`
+	Auth   testCredentials   `json:"auth"`
+	Tokens []testCredentials `json:"tokens"`
+	Header map[string]string `json:"header"`
+}
+
+func TestDeepRedactKeys(t *testing.T) {
+	req := testRequest{
+		Path:   "/login",
+		Auth:   testCredentials{User: "u1", Password: "secret1"},
+		Tokens: []testCredentials{{User: "u2", Password: "secret2"}},
+		Header: map[string]string{"X-Api-Token": "secret3"},
+	}
+	l, read := newTestLogger(t, &Config{DeepRedactKeys: []string{"password", "*-token"}})
+	l.Info("redacted", zap.Any("req", req), zap.String("Password", "secret4"), SortedMapField("m", map[string]string{"password": "secret5"}))
+	out := read()
+	assert.NotContains(t, out, "secret")
+	assert.Contains(t, out, `{"req":{"path":"/login","auth":{"user":"u1","password":"***"},`+
+		`"tokens":[{"user":"u2","password":"***"}],"header":{"X-Api-Token":"***"}},"Password":"***","m":{"password":"***"}}`)
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("plain", zap.Any("req", req))
+	assert.Contains(t, read(), `"password":"secret1"`)
+}
+
+// syncCounter is a WriteSyncer counting the calls to Sync.
+type syncCounter struct {
+	syncs int
+}
+
+func (s *syncCounter) Write(p []byte) (int, error) { return len(p), nil }
+
+func (s *syncCounter) Sync() error {
+	s.syncs++
+	return nil
+}
+
+func newSyncCountingLogger(s *syncCounter) *zap.Logger {
+	return zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), s, zap.DebugLevel))
+}
+
+func TestSyncNamed(t *testing.T) {
+	loggerInitOnce.Do(func() {})
+	sysLoggerInitOnce.Do(func() {})
+	tracingLoggerInitOnce.Do(func() {})
+	accessLoggerInitOnce.Do(func() {})
+	auditLoggerInitOnce.Do(func() {})
+	oldLogger, oldSysLogger, oldTracingLogger := logger.Load(), sysLogger.Load(), tracingLogger.Load()
+	oldAccessLogger, oldAuditLogger := accessLogger.Load(), auditLogger.Load()
+	defer func() {
+		logger.Store(oldLogger)
+		sysLogger.Store(oldSysLogger)
+		tracingLogger.Store(oldTracingLogger)
+		accessLogger.Store(oldAccessLogger)
+		auditLogger.Store(oldAuditLogger)
+	}()
+
+	counters := map[string]*syncCounter{"default": {}, "sys": {}, "tracing": {}, "access": {}, "audit": {}}
+	logger.Store(newSyncCountingLogger(counters["default"]))
+	sysLogger.Store(newSyncCountingLogger(counters["sys"]))
+	tracingLogger.Store(newSyncCountingLogger(counters["tracing"]))
+	accessLogger.Store(newSyncCountingLogger(counters["access"]))
+	auditLogger.Store(newSyncCountingLogger(counters["audit"]))
+
+	for _, name := range []string{"default", "sys", "tracing", "access", "audit"} {
+		assert.Nil(t, SyncNamed(name))
+		for other, counter := range counters {
+			if other == name {
+				assert.Equal(t, 1, counter.syncs, other)
+			} else {
+				assert.Equal(t, 0, counter.syncs, other)
+			}
+		}
+		counters[name].syncs = 0
+	}
+
+	assert.ErrorContains(t, SyncNamed("payments"), `unknown logger "payments"`)
+}
+
+func TestHumanReadableDurations(t *testing.T) {
+	l, read := newTestLogger(t, &Config{HumanReadableDurations: true})
+	l.Info("durations", zap.Duration("ns", 800*time.Nanosecond), zap.Duration("ms", 250*time.Millisecond),
+		zap.Duration("s", 1500*time.Millisecond), zap.Duration("h", 90*time.Minute))
+	assert.Contains(t, read(), `{"ns":"800ns","ms":"250ms","s":"1.5s","h":"1h30m0s"}`)
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("durations", zap.Duration("ms", 250*time.Millisecond), zap.Duration("s", 1500*time.Millisecond))
+	assert.Contains(t, read(), `{"ms":250,"s":1500}`)
+
+	// the JSON logs keep the milliseconds for the machines
+	l, read = newTestLogger(t, &Config{HumanReadableDurations: true, Encoding: EncodingJSON})
+	l.Info("durations", zap.Duration("s", 1500*time.Millisecond))
+	assert.Contains(t, read(), `"msg":"durations","s":1500}`)
+}
+
+func TestTimeEncoding(t *testing.T) {
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	l, read := newTestLogger(t, &Config{TimeEncoding: TimeEncodingEpochMillis})
+	l.Info("epoch millis")
+	out := read()
+	assert.Regexp(t, `^\d{13}\|info\|`, out)
+	millis, err := strconv.ParseInt(out[:strings.Index(out, "|")], 10, 64)
+	assert.Nil(t, err)
+	assert.True(t, millis >= before && millis <= time.Now().UnixNano()/int64(time.Millisecond), out)
+
+	l, read = newTestLogger(t, &Config{TimeEncoding: TimeEncodingEpoch})
+	l.Info("epoch")
+	assert.Regexp(t, `^\d{10}(\.\d+)?\|info\|`, read())
+
+	l, read = newTestLogger(t, &Config{TimeLayout: time.RFC3339})
+	l.Info("layout")
+	out = read()
+	_, err = time.Parse(time.RFC3339, out[:strings.Index(out, "|")])
+	assert.Nil(t, err, out)
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("default")
+	out = read()
+	_, err = time.Parse(customTimeLayout, out[:strings.Index(out, "|")])
+	assert.Nil(t, err, out)
+}
+
+func TestConsoleSeparator(t *testing.T) {
+	l, read := newTestLogger(t, &Config{ConsoleSeparator: "\t"})
+	l.With(zap.String(TraceKey, "trace-id")).Info("tabs", zap.Int("a", 1))
+	out := read()
+	assert.Regexp(t, "^[^\t|]+\tinfo\t[^\t]*logger_test\\.go:\\d+\ttrace-id\ttabs\t"+`\{"a":1\}`+"\n$", out)
+
+	l, read = newTestLogger(t, &Config{})
+	l.Info("pipes")
+	assert.Contains(t, read(), "|info|")
+}
+
+func TestEmitTraceAsField(t *testing.T) {
+	l, read := newTestLogger(t, &Config{EmitTraceAsField: true})
+	l.With(zap.String(TraceKey, "trace-id")).Info("with trace field", zap.Int("a", 1))
+	assert.Contains(t, read(), `|trace-id|with trace field|{"@jiao_trace_id":"trace-id","a":1}`)
+}
+
+func TestFieldPrefix(t *testing.T) {
+	l, read := newTestLogger(t, &Config{FieldPrefix: "payments."})
+	l.With(zap.Int("id", 1)).Info("prefix", zap.String("status", "ok"),
+		zap.Any("order", map[string]int{"amount": 2}), zap.Namespace("ns"), zap.Int("inner", 3))
+	out := read()
+	assert.Contains(t, out, `{"payments.id":1,"payments.status":"ok","payments.order":{"amount":2},"payments.ns":{"inner":3}}`)
+	assert.Contains(t, out, "|info|")
+}
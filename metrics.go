@@ -0,0 +1,35 @@
+package log
+
+import (
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelCounts are the numbers of logs written by level, indexed from DebugLvl to FatalLvl.
+var levelCounts [FatalLvl - DebugLvl + 1]atomic.Uint64
+
+// countLevel is the hook installed by newLogger, called once for each log written into at least one file.
+var countLevel = zap.Hooks(func(entry zapcore.Entry) error {
+	if entry.Level >= DebugLvl && entry.Level <= FatalLvl {
+		levelCounts[entry.Level-DebugLvl].Inc()
+	}
+	return nil
+})
+
+// Metrics - Return the numbers of logs written by level since the start of the process (or ResetMetrics),
+// by all the loggers, e.g. to export the rate of errors and warnings as Prometheus metrics.
+func Metrics() map[LogLevel]uint64 {
+	metrics := make(map[LogLevel]uint64, len(levelCounts))
+	for i := range levelCounts {
+		metrics[DebugLvl+LogLevel(i)] = levelCounts[i].Load()
+	}
+	return metrics
+}
+
+// ResetMetrics - Reset the numbers of logs returned by Metrics to zero, e.g. between tests.
+func ResetMetrics() {
+	for i := range levelCounts {
+		levelCounts[i].Store(0)
+	}
+}
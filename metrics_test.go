@@ -0,0 +1,30 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	ResetMetrics()
+	defer ResetMetrics()
+
+	l, _ := newTestLogger(t, &Config{})
+	for i := 0; i < 3; i++ {
+		l.Error("error")
+	}
+	for i := 0; i < 5; i++ {
+		l.Warn("warn")
+	}
+	l.Debug("below the level")
+
+	metrics := Metrics()
+	assert.Equal(t, uint64(3), metrics[ErrorLvl])
+	assert.Equal(t, uint64(5), metrics[WarnLvl])
+	assert.Equal(t, uint64(0), metrics[DebugLvl])
+	assert.Len(t, metrics, 7)
+
+	ResetMetrics()
+	assert.Equal(t, uint64(0), Metrics()[ErrorLvl])
+}
@@ -0,0 +1,51 @@
+package otelbridge
+
+import (
+	"context"
+	crand "crypto/rand"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+type spanIDsKey struct{}
+
+// spanIDs are the ids of a span of the trace package, passed by Report to the IDGenerator in the context.
+type spanIDs struct {
+	traceID  oteltrace.TraceID
+	spanID   oteltrace.SpanID
+	parentID oteltrace.SpanID
+}
+
+type idGenerator struct{}
+
+// IDGenerator returns an id generator to build the OpenTelemetry TracerProvider given to OTelReporter with,
+// e.g. sdktrace.NewTracerProvider(sdktrace.WithIDGenerator(otelbridge.IDGenerator())), so the exported spans
+// keep the ids of the spans of the trace package. The spans not exported by OTelReporter get random ids.
+func IDGenerator() sdktrace.IDGenerator {
+	return idGenerator{}
+}
+
+// NewIDs returns the ids of a root span.
+func (idGenerator) NewIDs(ctx context.Context) (oteltrace.TraceID, oteltrace.SpanID) {
+	if ids, ok := ctx.Value(spanIDsKey{}).(spanIDs); ok {
+		return ids.traceID, ids.spanID
+	}
+	var traceID oteltrace.TraceID
+	_, _ = crand.Read(traceID[:])
+	return traceID, randomSpanID()
+}
+
+// NewSpanID returns the id of a span whose parent is in ctx.
+func (idGenerator) NewSpanID(ctx context.Context, traceID oteltrace.TraceID) oteltrace.SpanID {
+	if ids, ok := ctx.Value(spanIDsKey{}).(spanIDs); ok && ids.traceID == traceID {
+		return ids.spanID
+	}
+	return randomSpanID()
+}
+
+func randomSpanID() oteltrace.SpanID {
+	var spanID oteltrace.SpanID
+	_, _ = crand.Read(spanID[:])
+	return spanID
+}
@@ -0,0 +1,94 @@
+// Package otelbridge exports the spans of the trace package to an OpenTelemetry TracerProvider.
+// It is a separate package so OpenTelemetry is only linked into the services using it.
+package otelbridge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/caser789/logger/internal/trace"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// instrumentationName is the name of the OpenTelemetry tracer of the exported spans.
+	instrumentationName = "github.com/caser789/logger"
+	// ReqTypeKey is the attribute of the request type of the span context, e.g. "debug" or "stress_test".
+	ReqTypeKey = "req_type"
+	// logEventName is the name of the events of the logs of the spans, see trace.Span.LogFields.
+	logEventName = "log"
+)
+
+type otelReporter struct {
+	tracer oteltrace.Tracer
+}
+
+// OTelReporter returns a trace.Reporter, to pair with trace.NewReportingTracer, exporting each finished span
+// into tp with its timestamps, parent, tags (as attributes), logs (as events) and request type (as ReqTypeKey).
+// The OpenTelemetry spans keep the trace id of the spans, and their span id if tp is built with IDGenerator.
+func OTelReporter(tp oteltrace.TracerProvider) trace.Reporter {
+	return &otelReporter{tracer: tp.Tracer(instrumentationName)}
+}
+
+// Install sets the global tracer of the trace package to a reporting tracer exporting its spans into tp.
+func Install(tp oteltrace.TracerProvider) {
+	trace.SetGlobalTracer(trace.NewReportingTracer(OTelReporter(tp), trace.NewSpanContextGenerator("")))
+}
+
+// Report implements trace.Reporter, it is called when the span is finished.
+func (r *otelReporter) Report(spanContext trace.SpanContext, name string, tags map[string]interface{},
+	logs []trace.LogRecord, start, finish time.Time) {
+	var ids spanIDs
+	copy(ids.traceID[:], spanContext.TraceID())
+	copy(ids.spanID[:], spanContext.SpanID())
+	copy(ids.parentID[:], spanContext.ParentID())
+	ctx := context.WithValue(context.Background(), spanIDsKey{}, ids)
+	if ids.parentID.IsValid() {
+		ctx = oteltrace.ContextWithRemoteSpanContext(ctx, oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    ids.traceID,
+			SpanID:     ids.parentID,
+			TraceFlags: oteltrace.FlagsSampled,
+			Remote:     true,
+		}))
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(tags)+1)
+	attrs = append(attrs, attribute.String(ReqTypeKey, trace.GetRequestType(spanContext)))
+	attrs = append(attrs, toAttributes(tags)...)
+	_, span := r.tracer.Start(ctx, name, oteltrace.WithTimestamp(start), oteltrace.WithAttributes(attrs...))
+	for _, record := range logs {
+		span.AddEvent(logEventName, oteltrace.WithTimestamp(record.Time),
+			oteltrace.WithAttributes(toAttributes(record.Fields)...))
+	}
+	span.End(oteltrace.WithTimestamp(finish))
+}
+
+// toAttributes converts the values of m, those of unsupported types are formatted with fmt.
+func toAttributes(m map[string]interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			attrs = append(attrs, attribute.String(k, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(k, v))
+		case int:
+			attrs = append(attrs, attribute.Int(k, v))
+		case int32:
+			attrs = append(attrs, attribute.Int64(k, int64(v)))
+		case int64:
+			attrs = append(attrs, attribute.Int64(k, v))
+		case float32:
+			attrs = append(attrs, attribute.Float64(k, float64(v)))
+		case float64:
+			attrs = append(attrs, attribute.Float64(k, v))
+		case []string:
+			attrs = append(attrs, attribute.StringSlice(k, v))
+		default:
+			attrs = append(attrs, attribute.String(k, fmt.Sprint(v)))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,86 @@
+package otelbridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caser789/logger/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracer(t *testing.T) (trace.Tracer, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithIDGenerator(IDGenerator()))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return trace.NewReportingTracer(OTelReporter(tp), trace.NewSpanContextGenerator("")), exporter
+}
+
+func TestOTelReporter(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	sampled := true
+	sc := trace.NewSpanContextGenerator("").NewSpanContext(trace.IsSampled(&sampled), trace.IsFromStressTest(true))
+	root, err := tracer.NewSpanWithOptions("root", sc, trace.StartTime(now.Add(-time.Second)))
+	assert.Nil(t, err)
+	root.SetTags("user", "alice", "retries", 2)
+	root.LogFields("event", "cache miss")
+	child, err := root.NewChildSpan("child")
+	assert.Nil(t, err)
+	started := time.Now()
+	time.Sleep(time.Millisecond)
+	child.Finish()
+	root.FinishWithOptions(trace.FinishTime(now))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	childSpan, rootSpan := spans[0], spans[1]
+
+	assert.Equal(t, "root", rootSpan.Name)
+	assert.Equal(t, sc.TraceID(), traceIDBytes(rootSpan.SpanContext.TraceID()))
+	assert.Equal(t, sc.SpanID(), spanIDBytes(rootSpan.SpanContext.SpanID()))
+	assert.False(t, rootSpan.Parent.IsValid())
+	assert.Equal(t, now.Add(-time.Second), rootSpan.StartTime)
+	assert.Equal(t, now, rootSpan.EndTime)
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String(ReqTypeKey, trace.ReqTypeStressTest),
+		attribute.String("user", "alice"),
+		attribute.Int("retries", 2),
+	}, rootSpan.Attributes)
+	assert.Len(t, rootSpan.Events, 1)
+	assert.Equal(t, logEventName, rootSpan.Events[0].Name)
+	assert.WithinDuration(t, time.Now(), rootSpan.Events[0].Time, time.Minute)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("event", "cache miss")}, rootSpan.Events[0].Attributes)
+
+	assert.Equal(t, "child", childSpan.Name)
+	assert.Equal(t, rootSpan.SpanContext.TraceID(), childSpan.SpanContext.TraceID())
+	assert.Equal(t, rootSpan.SpanContext.SpanID(), childSpan.Parent.SpanID())
+	assert.True(t, childSpan.Parent.IsRemote())
+	// the child started when created, before started, and finished after it
+	assert.False(t, childSpan.StartTime.After(started), childSpan.StartTime)
+	assert.True(t, childSpan.EndTime.After(started), childSpan.EndTime)
+	assert.WithinDuration(t, started, childSpan.StartTime, time.Minute)
+}
+
+func TestOTelReporterNotSampled(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+
+	sampled := false
+	span, err := tracer.NewSpan("dropped", trace.NewSpanContextGenerator("").NewSpanContext(trace.IsSampled(&sampled)))
+	assert.Nil(t, err)
+	span.Finish()
+	assert.Empty(t, exporter.GetSpans())
+}
+
+func traceIDBytes(id oteltrace.TraceID) []byte {
+	return id[:]
+}
+
+func spanIDBytes(id oteltrace.SpanID) []byte {
+	return id[:]
+}
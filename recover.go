@@ -0,0 +1,57 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// PanicKey is the key of the recovered value in the logs of RecoverAndLog.
+const PanicKey = "panic"
+
+// RecoverOption - An option of RecoverAndLogWithOptions.
+type RecoverOption func(*recoverOptions)
+
+type recoverOptions struct {
+	swallow bool
+}
+
+// WithSwallow - Don't re-panic after logging the panic, so the goroutine returns normally.
+func WithSwallow(swallow bool) RecoverOption {
+	return func(o *recoverOptions) {
+		o.swallow = swallow
+	}
+}
+
+// RecoverAndLog - Log the panic of the goroutine at error level, with its stack and the trace id of ctx,
+// then panic again with the same value. It must be deferred directly, e.g. defer log.RecoverAndLog(ctx),
+// and does nothing without a panic.
+func RecoverAndLog(ctx context.Context) {
+	if r := recover(); r != nil {
+		logPanic(ctx, r)
+		panic(r)
+	}
+}
+
+// RecoverAndLogWithOptions - Like RecoverAndLog, but WithSwallow(true) recovers from the panic after logging it.
+func RecoverAndLogWithOptions(ctx context.Context, opts ...RecoverOption) {
+	if r := recover(); r != nil {
+		o := recoverOptions{}
+		for _, opt := range opts {
+			opt(&o)
+		}
+		logPanic(ctx, r)
+		if !o.swallow {
+			panic(r)
+		}
+	}
+}
+
+func logPanic(ctx context.Context, r interface{}) {
+	fields := []zap.Field{zap.Any(PanicKey, r)}
+	if !stacktraceDisabled.Load() {
+		// skip logPanic and the deferred helper, the stack starts at the panic
+		fields = append(fields, zap.StackSkip("stacktrace", 2))
+	}
+	TraceLogger(ctx).Error("recovered from panic", fields...)
+}
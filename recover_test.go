@@ -0,0 +1,48 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caser789/logger/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRecoverAndLog(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	sc := trace.NewSpanContextGenerator("").NewSpanContext()
+	ctx := WithLogger(WithSpanContext(context.Background(), sc), l.With(zap.String(TraceKey, sc.String())))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer RecoverAndLog(ctx)
+		panic("boom")
+	})
+	out := read()
+	assert.Contains(t, out, "|"+sc.String()+"|recovered from panic|")
+	assert.Contains(t, out, `"panic":"boom"`)
+	assert.Contains(t, out, "TestRecoverAndLog")
+
+	// no-op without a panic
+	func() {
+		defer RecoverAndLog(ctx)
+	}()
+	assert.Equal(t, out, read())
+}
+
+func TestRecoverAndLogWithOptions(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	ctx := WithLogger(context.Background(), l)
+
+	assert.NotPanics(t, func() {
+		defer RecoverAndLogWithOptions(ctx, WithSwallow(true))
+		panic("swallowed")
+	})
+	assert.Contains(t, read(), `"panic":"swallowed"`)
+
+	assert.Panics(t, func() {
+		defer RecoverAndLogWithOptions(ctx)
+		panic("again")
+	})
+	assert.Contains(t, read(), `"panic":"again"`)
+}
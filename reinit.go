@@ -0,0 +1,44 @@
+package log
+
+import "go.uber.org/zap"
+
+// ReinitLogger - Rebuild the logger, system logger, tracing logger, access logger and audit logger with config,
+// e.g. after the config is reloaded, and apply its level and settings like InitLogger, which only runs once.
+// Each logger is swapped atomically, so it can be got concurrently, e.g. by GetLogger.
+// The previous loggers are flushed and their log files closed once the new ones are in place.
+// Loggers obtained before the call (e.g. by GetLogger, or held in a context) keep pointing at the previous cores:
+// their plain log files are reopened if still used, but their Async queues, Kafka, syslog and journald sinks
+// are closed and drop the logs. Get the loggers again after the call.
+func ReinitLogger(config *Config) {
+	rotateMutex.Lock()
+	defer rotateMutex.Unlock()
+
+	applySettings(config)
+	c := *config
+	initConfigMutex.Lock()
+	initConfig = &c
+	initConfigMutex.Unlock()
+
+	// make sure the lazy initialization won't replace the new loggers
+	loggerInitOnce.Do(func() {})
+	sysLoggerInitOnce.Do(func() {})
+	tracingLoggerInitOnce.Do(func() {})
+	accessLoggerInitOnce.Do(func() {})
+	auditLoggerInitOnce.Do(func() {})
+
+	olds := []*zap.Logger{logger.Load(), sysLogger.Load(), tracingLogger.Load(), accessLogger.Load(), auditLogger.Load()}
+	oldSinks, oldWriters := globalSinks(), globalFileWriters()
+	for _, initFunc := range []func(*Config){initDefaultLogger, initSystemLogger, initTracingLogger, initAccessLogger,
+		initAuditLogger} {
+		c := *config
+		initFunc(&c)
+	}
+	for _, old := range olds {
+		if old != nil {
+			_ = old.Sync()
+		}
+	}
+	// the sinks first, as they may write into the files
+	_ = closeSinks(oldSinks)
+	_ = closeFileWriters(oldWriters)
+}
@@ -0,0 +1,173 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestReinitLogger(t *testing.T) {
+	oldLogger, oldSysLogger, oldTracingLogger := logger.Load(), sysLogger.Load(), tracingLogger.Load()
+	oldAccessLogger, oldAuditLogger := accessLogger.Load(), auditLogger.Load()
+	oldLevel := GetLevel()
+	defer func() {
+		logger.Store(oldLogger)
+		sysLogger.Store(oldSysLogger)
+		tracingLogger.Store(oldTracingLogger)
+		accessLogger.Store(oldAccessLogger)
+		auditLogger.Store(oldAuditLogger)
+		SetLevel(oldLevel, 0)
+		initConfigMutex.Lock()
+		initConfig = nil
+		initConfigMutex.Unlock()
+	}()
+
+	dir := t.TempDir()
+	ReinitLogger(&Config{Path: dir, LogFileName: "first", Level: InfoLvl})
+	GetLogger().Info("to first")
+	GetLogger().Debug("filtered")
+
+	ReinitLogger(&Config{Path: dir, LogFileName: "second", Level: DebugLvl})
+	GetLogger().Debug("to second")
+	assert.Nil(t, Sync())
+
+	first, err := os.ReadFile(filepath.Join(dir, "first.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(first), "to first")
+	assert.NotContains(t, string(first), "filtered")
+	assert.NotContains(t, string(first), "to second")
+
+	second, err := os.ReadFile(filepath.Join(dir, "second.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(second), "to second")
+
+	// the writers of the previous files are closed
+	stats := WriterStats()
+	assert.NotContains(t, stats, filepath.Join(dir, "first.log"))
+	assert.Contains(t, stats, filepath.Join(dir, "second.log"))
+	assert.Contains(t, stats, filepath.Join(dir, SysLogFileName+".log"))
+}
+
+func TestGlobalFields(t *testing.T) {
+	oldLogger, oldSysLogger, oldTracingLogger := logger.Load(), sysLogger.Load(), tracingLogger.Load()
+	oldAccessLogger, oldAuditLogger := accessLogger.Load(), auditLogger.Load()
+	defer func() {
+		logger.Store(oldLogger)
+		sysLogger.Store(oldSysLogger)
+		tracingLogger.Store(oldTracingLogger)
+		accessLogger.Store(oldAccessLogger)
+		auditLogger.Store(oldAuditLogger)
+		initConfigMutex.Lock()
+		initConfig = nil
+		initConfigMutex.Unlock()
+	}()
+
+	dir := t.TempDir()
+	ReinitLogger(&Config{Path: dir, GlobalFields: []zap.Field{zap.String("service", "payments")}})
+	GetLogger().With(zap.Int("id", 1)).Info("user")
+	GetSysLogger().Info("sys")
+	tracingLogger.Load().Info("tracing")
+	assert.Nil(t, Sync())
+
+	for _, name := range []string{DefaultLogFileName, SysLogFileName, DefaultTracingFileName} {
+		data, err := os.ReadFile(filepath.Join(dir, name+".log"))
+		assert.Nil(t, err)
+		assert.Contains(t, string(data), `"service":"payments"`, name)
+	}
+}
+
+func TestResolveLogPaths(t *testing.T) {
+	oldLogger, oldSysLogger, oldTracingLogger := logger.Load(), sysLogger.Load(), tracingLogger.Load()
+	oldAccessLogger, oldAuditLogger := accessLogger.Load(), auditLogger.Load()
+	oldLevel := GetLevel()
+	defer func() {
+		logger.Store(oldLogger)
+		sysLogger.Store(oldSysLogger)
+		tracingLogger.Store(oldTracingLogger)
+		accessLogger.Store(oldAccessLogger)
+		auditLogger.Store(oldAuditLogger)
+		SetLevel(oldLevel, 0)
+		initConfigMutex.Lock()
+		initConfig = nil
+		initConfigMutex.Unlock()
+	}()
+
+	config := &Config{Path: t.TempDir(), SplitLevel: SplitWarn, Level: DebugLvl}
+	paths := ResolveLogPaths(config)
+	assert.Equal(t, "", config.LogFileName, "config isn't modified")
+
+	ReinitLogger(config)
+	for _, lvl := range []LogLevel{DebugLvl, InfoLvl, WarnLvl, ErrorLvl} {
+		GetLogger().Check(lvl, "user").Write()
+		GetSysLogger().Check(lvl, "sys").Write()
+	}
+	tracingLogger.Load().Info("tracing")
+	accessLogger.Load().Info("access")
+	auditLogger.Load().Info("audit")
+	assert.Nil(t, Sync())
+
+	var created []string
+	assert.Nil(t, filepath.Walk(config.Path, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			created = append(created, path)
+		}
+		return err
+	}))
+	assert.Equal(t, created, paths)
+	assert.Len(t, paths, 8)
+	assert.Contains(t, paths, filepath.Join(config.Path, "warn.log"))
+	assert.Contains(t, paths, filepath.Join(config.Path, SysErrorLogFileName+".log"))
+
+	// the logs printed into stdout have no file
+	paths = ResolveLogPaths(&Config{Path: config.Path, PrintToStd: PrintToStd_USERLOG | PrintToStd_TRACING})
+	assert.Equal(t, []string{
+		filepath.Join(config.Path, AccessLogFileName+".log"),
+		filepath.Join(config.Path, AuditLogFileName+".log"),
+		filepath.Join(config.Path, SysLogFileName+".log"),
+		filepath.Join(config.Path, SysErrorLogFileName+".log"),
+	}, paths)
+}
+
+func TestReinitLoggerConcurrentGet(t *testing.T) {
+	oldLogger, oldSysLogger, oldTracingLogger := logger.Load(), sysLogger.Load(), tracingLogger.Load()
+	oldAccessLogger, oldAuditLogger := accessLogger.Load(), auditLogger.Load()
+	defer func() {
+		logger.Store(oldLogger)
+		sysLogger.Store(oldSysLogger)
+		tracingLogger.Store(oldTracingLogger)
+		accessLogger.Store(oldAccessLogger)
+		auditLogger.Store(oldAuditLogger)
+		initConfigMutex.Lock()
+		initConfig = nil
+		initConfigMutex.Unlock()
+	}()
+
+	// run with -race: the loggers are read while being swapped
+	dir := t.TempDir()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				GetLogger().Info("concurrent")
+				GetSysLogger().Info("concurrent")
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		ReinitLogger(&Config{Path: dir, LogFileName: fmt.Sprintf("app%d", i), Level: InfoLvl})
+	}
+	close(done)
+	wg.Wait()
+	assert.Nil(t, Sync())
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/caser789/logger/internal/utils/env"
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+)
+
+const datedDirLayout = "2006-01-02"
+
+var (
+	initConfig      *Config
+	initConfigMutex sync.Mutex
+	rotateMutex     sync.Mutex
+)
+
+func setInitConfig(config *Config) {
+	initConfigMutex.Lock()
+	defer initConfigMutex.Unlock()
+	if initConfig == nil {
+		c := *config
+		initConfig = &c
+	}
+}
+
+// getInitConfig - Return a copy of the config passed to InitLogger, or the default config if InitLogger is not called.
+func getInitConfig() Config {
+	initConfigMutex.Lock()
+	defer initConfigMutex.Unlock()
+	if initConfig == nil {
+		return *getDefaultConfig()
+	}
+	return *initConfig
+}
+
+// RotateToDatedDir - Rebuild the logger, system logger, tracing logger, access logger and audit logger so that
+// they write into a sub directory named after the current date, e.g. ./log/2024-06-01/server.log, and flush and close
+// the previous ones. Each logger is swapped atomically like by ReinitLogger.
+// The per pod sub directory in K8S is kept under the dated directory.
+// It is meant to be called at midnight by a scheduler. Loggers obtained before the call keep writing to the previous files.
+func RotateToDatedDir() error {
+	rotateMutex.Lock()
+	defer rotateMutex.Unlock()
+
+	config := getInitConfig()
+	dir := config.Path
+	if dir == "" {
+		dir = env.GetLogDir()
+	}
+	config.Path = filepath.Join(dir, time.Now().Format(datedDirLayout))
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return err
+	}
+
+	// make sure the lazy initialization won't replace the new loggers
+	loggerInitOnce.Do(func() {})
+	sysLoggerInitOnce.Do(func() {})
+	tracingLoggerInitOnce.Do(func() {})
+	accessLoggerInitOnce.Do(func() {})
+	auditLoggerInitOnce.Do(func() {})
+
+	olds := []*zap.Logger{logger.Load(), sysLogger.Load(), tracingLogger.Load(), accessLogger.Load(), auditLogger.Load()}
+	oldSinks, oldWriters := globalSinks(), globalFileWriters()
+	for _, initFunc := range []func(*Config){initDefaultLogger, initSystemLogger, initTracingLogger, initAccessLogger,
+		initAuditLogger} {
+		c := config
+		initFunc(&c)
+	}
+	for _, old := range olds {
+		if old != nil {
+			_ = old.Sync()
+		}
+	}
+	// the sinks first, as they may write into the files
+	var res *multierror.Error
+	if err := closeSinks(oldSinks); err != nil {
+		res = multierror.Append(res, err)
+	}
+	if err := closeFileWriters(oldWriters); err != nil {
+		res = multierror.Append(res, err)
+	}
+	return res.ErrorOrNil()
+}
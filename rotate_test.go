@@ -0,0 +1,113 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateToDatedDir(t *testing.T) {
+	dir := t.TempDir()
+	initConfigMutex.Lock()
+	initConfig = &Config{Path: dir}
+	initConfigMutex.Unlock()
+	defer func() {
+		initConfigMutex.Lock()
+		initConfig = nil
+		initConfigMutex.Unlock()
+	}()
+
+	assert.Nil(t, RotateToDatedDir())
+	datedDir := filepath.Join(dir, time.Now().Format(datedDirLayout))
+	info, err := os.Stat(datedDir)
+	assert.Nil(t, err)
+	assert.True(t, info.IsDir())
+
+	GetLogger().Info("dated")
+	assert.Nil(t, GetLogger().Sync())
+	data, err := os.ReadFile(filepath.Join(datedDir, DefaultLogFileName+".log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "dated")
+
+	// the writers of the previous directory are closed and forgotten
+	dir2 := t.TempDir()
+	initConfigMutex.Lock()
+	initConfig = &Config{Path: dir2}
+	initConfigMutex.Unlock()
+	assert.Nil(t, RotateToDatedDir())
+	stats := WriterStats()
+	assert.NotContains(t, stats, filepath.Join(datedDir, DefaultLogFileName+".log"))
+	assert.Contains(t, stats, filepath.Join(dir2, time.Now().Format(datedDirLayout), DefaultLogFileName+".log"))
+}
+
+func TestRotateIntervalConfig(t *testing.T) {
+	config := &Config{Path: t.TempDir(), RotateInterval: time.Hour}
+	l := newLogger(getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	}))
+	l.Info("hourly")
+	assert.Nil(t, l.Sync())
+
+	name := filepath.Join(config.Path, "server-"+time.Now().UTC().Format("2006010215")+".log")
+	data, err := os.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "hourly")
+}
+
+// zoneClock reads the time in a zone, e.g. a local time zone other than the one of the machine.
+type zoneClock struct{ loc *time.Location }
+
+func (c zoneClock) Now() time.Time { return time.Now().In(c.loc) }
+
+func (c zoneClock) NewTicker(d time.Duration) *time.Ticker { return time.NewTicker(d) }
+
+func TestUseUTC(t *testing.T) {
+	clock := zoneClock{time.FixedZone("UTC+8", 8*60*60)}
+	config := &Config{Path: t.TempDir(), UseUTC: true}
+	opt := getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})
+	opt.Clock = clock
+	l := newLogger(opt)
+	l.Info("in utc")
+	assert.Nil(t, l.Sync())
+	assert.Nil(t, fileWriterOf(opt).Rotate())
+
+	data, err := os.ReadFile(filepath.Join(config.Path, "server.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "", string(data))
+	backups, err := filepath.Glob(filepath.Join(config.Path, "server-*.log"))
+	assert.Nil(t, err)
+	assert.Len(t, backups, 1)
+	data, err = os.ReadFile(backups[0])
+	assert.Nil(t, err)
+	line := string(data)
+	assert.Contains(t, line, "|in utc")
+
+	logged, err := time.Parse(customTimeLayout, line[:strings.Index(line, "|")])
+	assert.Nil(t, err)
+	_, offset := logged.Zone()
+	assert.Equal(t, 0, offset, line)
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(backups[0]), "server-"), ".log")
+	rotated, err := time.Parse("2006-01-02T15-04-05.000", name)
+	assert.Nil(t, err)
+	// both read the same clock in the same zone
+	assert.True(t, rotated.Sub(logged.Truncate(time.Millisecond)) >= 0 && rotated.Sub(logged) < time.Minute,
+		"%s %s", logged, rotated)
+
+	config = &Config{Path: t.TempDir()}
+	opt = getOption(config, "server", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})
+	opt.Clock = clock
+	l = newLogger(opt)
+	l.Info("in local time")
+	assert.Nil(t, l.Sync())
+	data, err = os.ReadFile(filepath.Join(config.Path, "server.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "+08:00|info|")
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	fieldSampleTick       = time.Second
+	fieldSampleFirst      = 100
+	fieldSampleThereafter = 100
+)
+
+// fieldSamplerCore samples entries per value of a field: within each tick, the first `first` entries
+// with the same value are logged, then every `thereafter`-th entry. Entries without the field are not sampled.
+type fieldSamplerCore struct {
+	zapcore.Core
+	field      string
+	key        string
+	hasKey     bool
+	counters   *fieldCounters
+	first      uint64
+	thereafter uint64
+}
+
+func newFieldSamplerCore(core zapcore.Core, field string, tick time.Duration, first, thereafter uint64) zapcore.Core {
+	return &fieldSamplerCore{
+		Core:       core,
+		field:      field,
+		counters:   &fieldCounters{tick: tick, counts: make(map[string]uint64)},
+		first:      first,
+		thereafter: thereafter,
+	}
+}
+
+func (c *fieldSamplerCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	if key, ok := c.fieldValue(fields); ok {
+		clone.key, clone.hasKey = key, true
+	}
+	return &clone
+}
+
+func (c *fieldSamplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return ce.AddCore(ent, c)
+}
+
+// Write samples the entry, then hands it to the wrapped core.
+// The wrapped core is checked again, as cores like the tee only filter levels in Check.
+func (c *fieldSamplerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key, hasKey := c.key, c.hasKey
+	if k, ok := c.fieldValue(fields); ok {
+		key, hasKey = k, true
+	}
+	if hasKey && !c.allow(key, ent.Time) {
+		return nil
+	}
+	if inner := c.Core.Check(ent, nil); inner != nil {
+		inner.Write(fields...)
+	}
+	return nil
+}
+
+func (c *fieldSamplerCore) allow(key string, t time.Time) bool {
+	n := c.counters.inc(key, t)
+	if n <= c.first {
+		return true
+	}
+	return c.thereafter > 0 && (n-c.first)%c.thereafter == 0
+}
+
+func (c *fieldSamplerCore) fieldValue(fields []zapcore.Field) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.Key != c.field {
+			continue
+		}
+		switch f.Type {
+		case zapcore.StringType:
+			return f.String, true
+		case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+			return strconv.FormatInt(f.Integer, 10), true
+		case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type:
+			return strconv.FormatUint(uint64(f.Integer), 10), true
+		case zapcore.BoolType:
+			return strconv.FormatInt(f.Integer, 10), true
+		case zapcore.Float64Type:
+			return strconv.FormatFloat(math.Float64frombits(uint64(f.Integer)), 'g', -1, 64), true
+		case zapcore.Float32Type:
+			return strconv.FormatFloat(float64(math.Float32frombits(uint32(f.Integer))), 'g', -1, 32), true
+		case zapcore.DurationType:
+			return time.Duration(f.Integer).String(), true
+		default:
+			return fmt.Sprint(f.Interface), true
+		}
+	}
+	return "", false
+}
+
+// fieldCounters counts entries per field value. All counters are dropped at each tick,
+// so the memory used is bounded by the number of values seen within one tick.
+type fieldCounters struct {
+	mutex   sync.Mutex
+	tick    time.Duration
+	resetAt time.Time
+	counts  map[string]uint64
+}
+
+func (fc *fieldCounters) inc(key string, t time.Time) uint64 {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if !t.Before(fc.resetAt) {
+		fc.counts = make(map[string]uint64)
+		fc.resetAt = t.Truncate(fc.tick).Add(fc.tick)
+	}
+	fc.counts[key]++
+	return fc.counts[key]
+}
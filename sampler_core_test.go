@@ -0,0 +1,71 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFieldSamplerCore(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	l = l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newFieldSamplerCore(core, "customer_id", time.Minute, 2, 3)
+	}))
+
+	for i := 0; i < 8; i++ {
+		l.Info("noisy", zap.String("customer_id", "a"))
+	}
+	l.Info("quiet", zap.String("customer_id", "b"))
+	l.With(zap.String("customer_id", "b")).Info("quiet")
+	l.Info("no key")
+	l.Info("no key")
+
+	lines := read()
+	// a: first 2, then every 3rd, i.e. the 5th and the 8th
+	assert.Equal(t, 4, strings.Count(lines, "noisy"))
+	assert.Equal(t, 2, strings.Count(lines, "quiet"))
+	assert.Equal(t, 2, strings.Count(lines, "no key"))
+}
+
+func TestFieldSamplerCoreLevel(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	l = l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newFieldSamplerCore(core, "customer_id", time.Minute, 1, 0)
+	}), zap.IncreaseLevel(zap.WarnLevel))
+
+	l.Info("dropped by level", zap.Int("customer_id", 1))
+	l.Warn("sampled", zap.Int("customer_id", 1))
+	l.Warn("sampled", zap.Int("customer_id", 1))
+
+	lines := read()
+	assert.NotContains(t, lines, "dropped by level")
+	assert.Equal(t, 1, strings.Count(lines, "sampled"))
+}
+
+func TestFieldSamplerCoreValueTypes(t *testing.T) {
+	c := &fieldSamplerCore{field: "k"}
+	tests := []struct {
+		a, b zap.Field
+	}{
+		{zap.Bool("k", true), zap.Bool("k", false)},
+		{zap.Float64("k", 1.5), zap.Float64("k", 2.5)},
+		{zap.Float32("k", 1.5), zap.Float32("k", 2.5)},
+		{zap.Duration("k", time.Second), zap.Duration("k", time.Minute)},
+	}
+	for _, tt := range tests {
+		a, ok := c.fieldValue([]zapcore.Field{tt.a})
+		assert.True(t, ok)
+		b, _ := c.fieldValue([]zapcore.Field{tt.b})
+		assert.NotEqual(t, a, b, tt.a.Type)
+	}
+	v, _ := c.fieldValue([]zapcore.Field{zap.Bool("k", true)})
+	assert.Equal(t, "1", v)
+	v, _ = c.fieldValue([]zapcore.Field{zap.Float64("k", 1.5)})
+	assert.Equal(t, "1.5", v)
+	v, _ = c.fieldValue([]zapcore.Field{zap.Duration("k", time.Second)})
+	assert.Equal(t, "1s", v)
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sequenceCore attaches to each entry written a sequence number under SeqKey, increasing by one
+// with each entry of the logger, including the loggers derived from it by With. It wraps to 0 after 2^64-1.
+type sequenceCore struct {
+	zapcore.Core
+	seq *atomic.Uint64
+}
+
+func newSequenceCore(core zapcore.Core) zapcore.Core {
+	return &sequenceCore{Core: core, seq: atomic.NewUint64(0)}
+}
+
+func (c *sequenceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sequenceCore{Core: c.Core.With(fields), seq: c.seq}
+}
+
+func (c *sequenceCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return ce.AddCore(ent, c)
+}
+
+// Write numbers the entry, then hands it to the wrapped core.
+// The wrapped core is checked first, so the entries it drops don't take a number.
+func (c *sequenceCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if inner := c.Core.Check(ent, nil); inner != nil {
+		inner.Write(append(fields[:len(fields):len(fields)], zap.Uint64(SeqKey, c.seq.Inc()))...)
+	}
+	return nil
+}
+
+// sequenceOptions - Return the options numbering the entries of a logger according to Config.IncludeSequence.
+func sequenceOptions(config *Config) []zap.Option {
+	if !config.IncludeSequence {
+		return nil
+	}
+	return []zap.Option{zap.WrapCore(newSequenceCore)}
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// readSeqs returns the seq field of each line.
+func readSeqs(t *testing.T, lines string) []uint64 {
+	var seqs []uint64
+	for _, line := range strings.Split(strings.TrimSpace(lines), "\n") {
+		var fields struct {
+			Seq uint64 `json:"seq"`
+		}
+		assert.Nil(t, json.Unmarshal([]byte(line[strings.Index(line, "{"):]), &fields))
+		seqs = append(seqs, fields.Seq)
+	}
+	return seqs
+}
+
+func TestSequenceCore(t *testing.T) {
+	config := &Config{IncludeSequence: true}
+	l, read := newTestLogger(t, config)
+	l = l.WithOptions(sequenceOptions(config)...)
+
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			l.Info("numbered")
+		} else {
+			l.With(zap.Int("i", i)).Info("numbered")
+		}
+	}
+	l.Debug("dropped by level")
+
+	seqs := readSeqs(t, read())
+	assert.Len(t, seqs, 100)
+	for i, seq := range seqs {
+		assert.Equal(t, uint64(i+1), seq)
+	}
+}
+
+func TestSequenceCoreWrap(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	l = l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		c := newSequenceCore(core)
+		c.(*sequenceCore).seq.Store(math.MaxUint64 - 1)
+		return c
+	}))
+
+	l.Info("last")
+	l.Info("wrapped")
+	l.Info("wrapped")
+
+	assert.Equal(t, []uint64{math.MaxUint64, 0, 1}, readSeqs(t, read()))
+}
+
+func TestSequenceOptionsOff(t *testing.T) {
+	assert.Empty(t, sequenceOptions(&Config{}))
+}
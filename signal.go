@@ -0,0 +1,64 @@
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	signalSyncMutex  sync.Mutex
+	signalSyncCancel func()
+
+	// syncOnSignal exists so it can be mocked out by tests.
+	syncOnSignal = Sync
+)
+
+// InstallSignalSync - Call Sync() when one of the signals is received, so buffered logs are not lost on shutdown.
+// SIGINT and SIGTERM are used if no signal is given.
+// After syncing, the handler uninstalls itself and re-sends the signal to the process, so the default action
+// of the signal (or any other handler registered by the application) still applies.
+// Calling it again while a handler is installed has no effect and returns the same cancel func.
+// The returned func uninstalls the handler.
+func InstallSignalSync(signals ...os.Signal) func() {
+	signalSyncMutex.Lock()
+	defer signalSyncMutex.Unlock()
+
+	if signalSyncCancel != nil {
+		return signalSyncCancel
+	}
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+
+			signalSyncMutex.Lock()
+			signalSyncCancel = nil
+			signalSyncMutex.Unlock()
+		})
+	}
+
+	signal.Notify(ch, signals...)
+	go func() {
+		select {
+		case sig := <-ch:
+			_ = syncOnSignal()
+			cancel()
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+
+	signalSyncCancel = cancel
+	return cancel
+}
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallSignalSync(t *testing.T) {
+	synced := make(chan struct{}, 1)
+	syncOnSignal = func() error {
+		synced <- struct{}{}
+		return nil
+	}
+	defer func() {
+		syncOnSignal = Sync
+	}()
+
+	// the application's own handler, which keeps the test process alive
+	appCh := make(chan os.Signal, 2)
+	signal.Notify(appCh, syscall.SIGHUP)
+	defer signal.Stop(appCh)
+
+	cancel := InstallSignalSync(syscall.SIGHUP)
+	defer cancel()
+	// idempotent
+	InstallSignalSync(syscall.SIGHUP)
+
+	p, err := os.FindProcess(os.Getpid())
+	assert.Nil(t, err)
+	assert.Nil(t, p.Signal(syscall.SIGHUP))
+
+	select {
+	case <-synced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sync was not called on signal")
+	}
+
+	// the application receives the signal, then the re-sent one
+	for i := 0; i < 2; i++ {
+		select {
+		case <-appCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("signal was not delivered to the application")
+		}
+	}
+
+	// the handler uninstalled itself
+	signalSyncMutex.Lock()
+	assert.Nil(t, signalSyncCancel)
+	signalSyncMutex.Unlock()
+}
+
+func TestInstallSignalSyncExits(t *testing.T) {
+	if os.Getenv("LOG_SIGNAL_SYNC_CHILD") != "" {
+		syncOnSignal = func() error {
+			fmt.Println("synced")
+			return nil
+		}
+		InstallSignalSync()
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(syscall.SIGTERM)
+		time.Sleep(5 * time.Second)
+		// still alive
+		os.Exit(0)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestInstallSignalSyncExits$")
+	cmd.Env = append(os.Environ(), "LOG_SIGNAL_SYNC_CHILD=1")
+	out, err := cmd.Output()
+	assert.Equal(t, "synced\n", string(out))
+	var exitErr *exec.ExitError
+	if assert.ErrorAs(t, err, &exitErr) {
+		status := exitErr.Sys().(syscall.WaitStatus)
+		assert.True(t, status.Signaled(), status)
+		assert.Equal(t, syscall.SIGTERM, status.Signal())
+	}
+}
+
+func TestInstallSignalSyncCancel(t *testing.T) {
+	cancel := InstallSignalSync()
+	cancel()
+	cancel()
+
+	// a new handler can be installed after cancel
+	cancel2 := InstallSignalSync()
+	defer cancel2()
+	signalSyncMutex.Lock()
+	assert.NotNil(t, signalSyncCancel)
+	signalSyncMutex.Unlock()
+}
@@ -0,0 +1,172 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"github.com/caser789/logger/internal/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	spanLogMsg        = "span"
+	spanBatchLogMsg   = "spans"
+	spanBatchInterval = time.Second
+	malformedSpanMsg  = "malformed span call"
+)
+
+var (
+	spanLogReporterMutex  sync.Mutex
+	activeSpanLogReporter *spanLogReporter
+)
+
+// EnableLoggingTracer - Set the global tracer to one writing each finished and sampled span into the tracing log,
+// with its trace id, name, tags, logs and duration. With Config.SpanBatchSize, spans are written in batches.
+// The malformed calls to the spans, e.g. SetTags with an odd number of arguments, are warned in the system log.
+func EnableLoggingTracer() {
+	config := getInitConfig()
+	r := newSpanLogReporter(GetTracingLogger, config.SpanBatchSize, spanBatchInterval)
+
+	spanLogReporterMutex.Lock()
+	prev := activeSpanLogReporter
+	activeSpanLogReporter = r
+	spanLogReporterMutex.Unlock()
+	if prev != nil {
+		prev.stop()
+	}
+
+	trace.SetErrorHandler(func(err error) {
+		GetSysLogger().Warn(malformedSpanMsg, zap.Error(err))
+	})
+	trace.SetGlobalTracer(trace.NewReportingTracer(r, getSpanContextGenerator()))
+}
+
+// flushSpanLogs - Write the spans batched by the logging tracer.
+func flushSpanLogs() {
+	spanLogReporterMutex.Lock()
+	r := activeSpanLogReporter
+	spanLogReporterMutex.Unlock()
+	if r != nil {
+		r.flush()
+	}
+}
+
+// loggedSpan is a finished span written into the tracing log.
+type loggedSpan struct {
+	traceID  string
+	name     string
+	tags     map[string]interface{}
+	logs     []trace.LogRecord
+	duration time.Duration
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (s loggedSpan) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", s.name)
+	enc.AddDuration("duration", s.duration)
+	if len(s.tags) > 0 {
+		if err := enc.AddReflected("tags", s.tags); err != nil {
+			return err
+		}
+	}
+	if len(s.logs) > 0 {
+		return enc.AddReflected("logs", s.logs)
+	}
+	return nil
+}
+
+type loggedSpans []loggedSpan
+
+// MarshalLogArray implements zapcore.ArrayMarshaler.
+func (ss loggedSpans) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, s := range ss {
+		if err := enc.AppendObject(zapcore.ObjectMarshalerFunc(func(oe zapcore.ObjectEncoder) error {
+			oe.AddString(TraceKey, s.traceID)
+			return s.MarshalLogObject(oe)
+		})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spanLogReporter is a trace.Reporter writing the spans into a logger, one entry per span,
+// or one entry per batch of spans when batchSize is more than 1. Batches are written when full,
+// every interval, and by Sync.
+type spanLogReporter struct {
+	logger    func() *zap.Logger
+	batchSize int
+
+	mutex sync.Mutex
+	batch loggedSpans
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newSpanLogReporter(logger func() *zap.Logger, batchSize int, interval time.Duration) *spanLogReporter {
+	r := &spanLogReporter{
+		logger:    logger,
+		batchSize: batchSize,
+		done:      make(chan struct{}),
+	}
+	if batchSize > 1 {
+		go r.run(interval)
+	}
+	return r
+}
+
+func (r *spanLogReporter) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.flush()
+		case <-r.done:
+			r.flush()
+			return
+		}
+	}
+}
+
+// Report implements trace.Reporter.
+func (r *spanLogReporter) Report(sc trace.SpanContext, name string, tags map[string]interface{}, logs []trace.LogRecord,
+	start, finish time.Time) {
+	s := loggedSpan{traceID: sc.String(), name: name, tags: tags, logs: logs, duration: finish.Sub(start)}
+	if r.batchSize <= 1 {
+		r.logger().With(zap.String(TraceKey, s.traceID)).Info(spanLogMsg, zap.Inline(s))
+		return
+	}
+
+	r.mutex.Lock()
+	r.batch = append(r.batch, s)
+	var full loggedSpans
+	if len(r.batch) >= r.batchSize {
+		full, r.batch = r.batch, nil
+	}
+	r.mutex.Unlock()
+	r.write(full)
+}
+
+func (r *spanLogReporter) flush() {
+	r.mutex.Lock()
+	batch := r.batch
+	r.batch = nil
+	r.mutex.Unlock()
+	r.write(batch)
+}
+
+func (r *spanLogReporter) write(batch loggedSpans) {
+	if len(batch) == 0 {
+		return
+	}
+	r.logger().Info(spanBatchLogMsg, zap.Array(spanBatchLogMsg, batch))
+}
+
+// stop writes the pending spans and stops the interval flush.
+func (r *spanLogReporter) stop() {
+	r.once.Do(func() {
+		close(r.done)
+	})
+}
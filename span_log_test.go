@@ -0,0 +1,97 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caser789/logger/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newSampledSpanContext() trace.SpanContext {
+	sampled := true
+	return trace.NewSpanContextGenerator("").NewSpanContext(trace.IsSampled(&sampled))
+}
+
+func TestSpanLogReporter(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	r := newSpanLogReporter(func() *zap.Logger { return l }, 0, time.Hour)
+	tracer := trace.NewReportingTracer(r, getSpanContextGenerator())
+
+	sc := newSampledSpanContext()
+	span, _ := tracer.NewSpan("query", sc)
+	span.SetTag("db", "users")
+	span.Finish()
+
+	out := read()
+	assert.Contains(t, out, "|"+sc.String()+"|span|")
+	assert.Contains(t, out, `"name":"query"`)
+	assert.Contains(t, out, `"tags":{"db":"users"}`)
+}
+
+func TestSpanLogReporterBatch(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	r := newSpanLogReporter(func() *zap.Logger { return l }, 3, time.Hour)
+	defer r.stop()
+	tracer := trace.NewReportingTracer(r, getSpanContextGenerator())
+
+	root, _ := tracer.NewSpan("root", newSampledSpanContext())
+	for _, name := range []string{"a", "b", "c", "d"} {
+		child, _ := root.NewChildSpan(name)
+		child.Finish()
+	}
+	out := read()
+	assert.Equal(t, 1, strings.Count(out, "|spans|"))
+	assert.Contains(t, out, `"name":"a"`)
+	assert.Contains(t, out, `"name":"c"`)
+	assert.NotContains(t, out, `"name":"d"`)
+
+	// the pending spans are written on flush
+	r.flush()
+	out = read()
+	assert.Equal(t, 2, strings.Count(out, "|spans|"))
+	assert.Contains(t, out, `"name":"d"`)
+}
+
+func TestEnableLoggingTracer(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	tracingLoggerInitOnce.Do(func() {})
+	oldTracingLogger, oldTracer := tracingLogger.Load(), trace.GlobalTracer()
+	tracingLogger.Store(l)
+	sysLoggerInitOnce.Do(func() {})
+	oldSysLogger := sysLogger.Load()
+	sysLogger.Store(l)
+	initConfigMutex.Lock()
+	oldConfig := initConfig
+	initConfig = &Config{SpanBatchSize: 10}
+	initConfigMutex.Unlock()
+	defer func() {
+		tracingLogger.Store(oldTracingLogger)
+		sysLogger.Store(oldSysLogger)
+		trace.SetGlobalTracer(oldTracer)
+		trace.SetErrorHandler(nil)
+		spanLogReporterMutex.Lock()
+		activeSpanLogReporter.stop()
+		activeSpanLogReporter = nil
+		spanLogReporterMutex.Unlock()
+		initConfigMutex.Lock()
+		initConfig = oldConfig
+		initConfigMutex.Unlock()
+	}()
+
+	l.Info("before")
+	EnableLoggingTracer()
+	ctx := WithSpanContext(context.Background(), newSampledSpanContext())
+	_, span := WithNewTraceLog("handler", ctx)
+	span.SetTags("status")
+	assert.Contains(t, read(), `malformed span call|{"error":"trace: SetTags of span \"handler\": odd number of arguments 1, key status has no value"}`)
+	span.Finish()
+	assert.NotContains(t, read(), `"name":"handler"`)
+
+	// unflushed spans are written on shutdown
+	_ = Sync()
+	assert.Contains(t, read(), `"name":"handler"`)
+}
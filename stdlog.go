@@ -0,0 +1,39 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+
+	"go.uber.org/zap"
+)
+
+// stdLogSkip skips the Write of stdLogWriter and the Output and Print of the standard library logger,
+// so the caller of the logs is the code printing them.
+var stdLogSkip = zap.AddCallerSkip(3)
+
+// StdLogger - Return a standard library logger writing each line printed with it into the user logger at level,
+// for the libraries only accepting a *log.Logger, e.g. as the ErrorLog of a net/http Server.
+func StdLogger(level LogLevel) *stdlog.Logger {
+	return newStdLogger(func() *zap.Logger { return GetLogger() }, level)
+}
+
+func newStdLogger(getLogger func() *zap.Logger, level LogLevel) *stdlog.Logger {
+	return stdlog.New(&stdLogWriter{getLogger: getLogger, level: level}, "", 0)
+}
+
+type stdLogWriter struct {
+	// getLogger is called on each write, so the logs follow ReinitLogger.
+	getLogger func() *zap.Logger
+	level     LogLevel
+}
+
+// Write - Log each line of p, without its trailing newline, as a message.
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	l := w.getLogger().WithOptions(stdLogSkip)
+	for _, line := range bytes.Split(bytes.TrimSuffix(p, []byte("\n")), []byte("\n")) {
+		if ce := l.Check(w.level, string(line)); ce != nil {
+			ce.Write()
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,30 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestStdLogger(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	std := newStdLogger(func() *zap.Logger { return l }, WarnLvl)
+	std.Print("from stdlib")
+	std.Printf("first line\nsecond line\n")
+
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasSuffix(lines[0], "|from stdlib"), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], "|first line"), lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], "|second line"), lines[2])
+	for _, line := range lines {
+		assert.Contains(t, line, "|warn|")
+		assert.Contains(t, line, "/stdlog_test.go:", line)
+	}
+
+	std = newStdLogger(func() *zap.Logger { return l }, DebugLvl)
+	std.Print("filtered")
+	assert.NotContains(t, read(), "filtered")
+}
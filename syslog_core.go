@@ -0,0 +1,103 @@
+package log
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/caser789/logger/internal/writer"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	syslogFailOnce      sync.Once
+	syslogWriteFailOnce sync.Once
+)
+
+// syslogCore sends each entry to syslog, with the severity of its level.
+// The entries that can't be sent, e.g. while the daemon restarts, are written into the log file instead.
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc      zapcore.Encoder
+	w        *writer.SyslogWriter
+	addr     string
+	fallback zapcore.Core
+}
+
+// newSyslogCore - Return the syslog core of opt, or nil if syslog is not enabled or can't be reached.
+// The failure is reported once on stderr, and the caller falls back to the log file.
+// Its connection is closed with the logger, see registerSink, and the later write failures are reported once too.
+func newSyslogCore(encoder zapcore.Encoder, opt option, lv zapcore.LevelEnabler) zapcore.Core {
+	if opt.SyslogAddr == "" {
+		return nil
+	}
+	tag := strings.TrimSuffix(filepath.Base(opt.Filename), filepath.Ext(opt.Filename))
+	w, err := writer.NewSyslogWriter(opt.SyslogAddr, tag)
+	if err != nil {
+		syslogFailOnce.Do(func() {
+			printStderr("can't connect to syslog %s, write logs into files instead: %v\n", opt.SyslogAddr, err)
+		})
+		return nil
+	}
+	registerSink(opt.Filename, w)
+	return &syslogCore{
+		LevelEnabler: lv,
+		enc:          encoder,
+		w:            w,
+		addr:         opt.SyslogAddr,
+		fallback:     zapcore.NewCore(encoder.Clone(), zapcore.AddSync(newFileWriter(opt)), lv),
+	}
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, w: c.w, addr: c.addr, fallback: c.fallback.With(fields)}
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	_, err = c.w.WriteSeverity(syslogSeverity(ent.Level), buf.Bytes())
+	if err == nil || errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	syslogWriteFailOnce.Do(func() {
+		printStderr("can't write to syslog %s, write logs into files until it's reachable: %v\n", c.addr, err)
+	})
+	return c.fallback.Write(ent, fields)
+}
+
+func (c *syslogCore) Sync() error {
+	return c.fallback.Sync()
+}
+
+// syslogSeverity - Return the syslog severity of lvl: debug 7, info 6, warn 4, error 3, and crit 2 above.
+func syslogSeverity(lvl LogLevel) int {
+	switch {
+	case lvl <= DebugLvl:
+		return 7
+	case lvl == InfoLvl:
+		return 6
+	case lvl == WarnLvl:
+		return 4
+	case lvl == ErrorLvl:
+		return 3
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,203 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// tailSubscriberQueueSize is the number of lines queued for each live tailing client.
+// Lines are dropped for a client which can't keep up.
+const tailSubscriberQueueSize = 256
+
+// tailBuf keeps the most recent user log lines, see Config.TailSize.
+var tailBuf = &tailBuffer{}
+
+type tailLine struct {
+	level LogLevel
+	text  string
+}
+
+// tailBuffer is a bounded ring of log lines, with live subscribers.
+type tailBuffer struct {
+	mutex sync.Mutex
+	lines []tailLine
+	next  int
+	full  bool
+	subs  map[chan tailLine]struct{}
+}
+
+func (b *tailBuffer) resize(size int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.lines = make([]tailLine, size)
+	b.next, b.full = 0, false
+}
+
+func (b *tailBuffer) add(line tailLine) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.lines) == 0 {
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// recent returns the buffered lines at or above minLevel, oldest first.
+func (b *tailBuffer) recent(minLevel LogLevel) []tailLine {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.recentLocked(minLevel)
+}
+
+func (b *tailBuffer) recentLocked(minLevel LogLevel) []tailLine {
+	var lines []tailLine
+	if b.full {
+		lines = append(lines, b.lines[b.next:]...)
+	}
+	lines = append(lines, b.lines[:b.next]...)
+
+	filtered := lines[:0]
+	for _, line := range lines {
+		if line.level >= minLevel {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered
+}
+
+// subscribe returns the recent lines at or above minLevel and a channel receiving the lines added afterwards.
+func (b *tailBuffer) subscribe(minLevel LogLevel) ([]tailLine, chan tailLine, func()) {
+	ch := make(chan tailLine, tailSubscriberQueueSize)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	lines := b.recentLocked(minLevel)
+	if b.subs == nil {
+		b.subs = make(map[chan tailLine]struct{})
+	}
+	b.subs[ch] = struct{}{}
+	return lines, ch, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.subs, ch)
+	}
+}
+
+// tailCore encodes the entries enabled by the user log level into a tailBuffer.
+type tailCore struct {
+	enc zapcore.Encoder
+	buf *tailBuffer
+}
+
+func newTailCore(enc zapcore.Encoder, buf *tailBuffer) zapcore.Core {
+	core := &tailCore{enc: enc, buf: buf}
+	return core.With([]zapcore.Field{zap.String(TraceKey, "-")})
+}
+
+func (c *tailCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= GetLevel()
+}
+
+func (c *tailCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &tailCore{enc: c.enc.Clone(), buf: c.buf}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *tailCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *tailCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.buf.add(tailLine{level: ent.Level, text: strings.TrimSuffix(buf.String(), "\n")})
+	buf.Free()
+	return nil
+}
+
+func (c *tailCore) Sync() error {
+	return nil
+}
+
+// TailHandler - Return a http handler serving the most recent user log lines kept in memory, see Config.TailSize.
+// It can be mounted on e.g. /debug/logtail.
+// Use ?level=error to only get the lines at or above a level.
+// Clients accepting text/event-stream get the recent lines and then the new lines as Server-Sent Events,
+// until they disconnect.
+func TailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		minLevel := DebugLvl
+		if s := r.URL.Query().Get("level"); s != "" {
+			if err := minLevel.UnmarshalText([]byte(s)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			for _, line := range tailBuf.recent(minLevel) {
+				fmt.Fprintln(w, line.text)
+			}
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		lines, ch, cancel := tailBuf.subscribe(minLevel)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		for _, line := range lines {
+			writeEvent(w, line.text)
+		}
+		flusher.Flush()
+		for {
+			select {
+			case line := <-ch:
+				if line.level >= minLevel {
+					writeEvent(w, line.text)
+					flusher.Flush()
+				}
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+}
+
+// writeEvent - Write text as one server-sent event, each of its lines (e.g. of a stacktrace) as a data field.
+func writeEvent(w io.Writer, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
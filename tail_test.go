@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTailTestLogger(t *testing.T) *zap.Logger {
+	return newTailTestLoggerConfig(t, &Config{TailSize: 3})
+}
+
+func newTailTestLoggerConfig(t *testing.T, config *Config) *zap.Logger {
+	opt := option{Filename: t.TempDir() + "/test.log", Lef: func(lvl LogLevel) bool {
+		return lvl >= GetLevel()
+	}}
+	return newLogger(opt).WithOptions(configOptions(config)...)
+}
+
+func TestTailHandlerRedacts(t *testing.T) {
+	type credentials struct {
+		User     string
+		Password string
+	}
+	l := newTailTestLoggerConfig(t, &Config{TailSize: 3, DeepRedactKeys: []string{"Password"}, MaxFieldBytes: 4})
+	l.Info("login", zap.Any("obj", credentials{User: "alice", Password: "hunter2"}), zap.String("long", "abcdefgh"))
+
+	server := httptest.NewServer(TailHandler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(body), `"Password":"***"`)
+	assert.NotContains(t, string(body), "hunter2")
+	assert.Contains(t, string(body), `"long":"abcd...(truncated)"`)
+}
+
+func TestTailHandler(t *testing.T) {
+	l := newTailTestLogger(t)
+	for _, msg := range []string{"first", "second", "third"} {
+		l.Info(msg)
+	}
+	l.Error("fourth")
+
+	server := httptest.NewServer(TailHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasSuffix(lines[0], "|second"), lines[0])
+	assert.True(t, strings.HasSuffix(lines[2], "|fourth"), lines[2])
+
+	resp, err = http.Get(server.URL + "?level=error")
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NotContains(t, string(body), "second")
+	assert.Contains(t, string(body), "|error|")
+
+	resp, err = http.Get(server.URL + "?level=bad")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestTailHandlerEventStream(t *testing.T) {
+	l := newTailTestLogger(t)
+	l.Info("before")
+
+	server := httptest.NewServer(TailHandler())
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, _ := reader.ReadString('\n')
+	assert.True(t, strings.HasPrefix(line, "data: "))
+	assert.Contains(t, line, "|before")
+	_, _ = reader.ReadString('\n')
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Info("live")
+		}()
+	}
+	wg.Wait()
+	line, _ = reader.ReadString('\n')
+	assert.Contains(t, line, "|live")
+}
+
+func TestWriteEvent(t *testing.T) {
+	var b strings.Builder
+	writeEvent(&b, "error|boom\nmain.main()\n\tmain.go:10")
+	assert.Equal(t, "data: error|boom\ndata: main.main()\ndata: \tmain.go:10\n\n", b.String())
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"context"
+	"sync"
 
 	"github.com/caser789/logger/internal/extension"
 	"github.com/caser789/logger/internal/trace"
@@ -9,10 +10,24 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	spanContextGenerator         trace.SpanContextGenerator
+	spanContextGeneratorInitOnce sync.Once
+)
+
+// getSpanContextGenerator - Return the generator used for new traces.
+// The sampling rate is read from the environment once, when the generator is built.
+func getSpanContextGenerator() trace.SpanContextGenerator {
+	spanContextGeneratorInitOnce.Do(func() {
+		spanContextGenerator = trace.NewSpanContextGenerator("")
+	})
+	return spanContextGenerator
+}
+
 func WithNewTraceLog(operationName string, ctx context.Context) (context.Context, trace.Span) {
 	spanCtx := GetSpanContext(ctx)
 	if spanCtx == nil {
-		spanCtx = trace.NewSpanContextGenerator("").NewSpanContext()
+		spanCtx = getSpanContextGenerator().NewSpanContextCtx(ctx)
 	}
 	span, _ := trace.GlobalTracer().NewSpan(operationName, spanCtx)
 	ctx = WithSpanContext(ctx, spanCtx)
@@ -28,12 +43,85 @@ func GetTraceIDFromCtx(ctx context.Context) string {
 	return ""
 }
 
+// IsInternalRequest - Return true if the span context in ctx is marked as originating from an internal service,
+// never for a trace id of the old format or generated by another lib.
+func IsInternalRequest(ctx context.Context) bool {
+	return trace.IsSpanContextInternal(GetSpanContext(ctx))
+}
+
+// IsEntrySpan - Return true if the span context in ctx was created from an inbound span context,
+// i.e. the request came from another service, and false for the root of a trace or without span context.
+func IsEntrySpan(ctx context.Context) bool {
+	return trace.IsCrossService(GetSpanContext(ctx))
+}
+
 func GetTraceLogFromCtx(ctx context.Context) *zap.Logger {
 	l := ctxzap.Extract(ctx)
-	if l.Core().Enabled(zap.FatalLevel) {
-		return l
+	if !l.Core().Enabled(zap.FatalLevel) {
+		l = GetLogger()
+	}
+	if fields := getFieldsFromCtx(ctx); len(fields) > 0 {
+		l = l.With(fields...)
+	}
+	if isSuppressed(ctx) {
+		l = l.WithOptions(zap.IncreaseLevel(zap.ErrorLevel))
+	}
+	return l
+}
+
+// TraceLogger - Return the logger of ctx with its trace id and the fields added by WithFields, resolved once,
+// so it can be held and reused to log many times with the same context, e.g. in a hot loop.
+func TraceLogger(ctx context.Context) *zap.Logger {
+	l := ctxzap.Extract(ctx)
+	if !l.Core().Enabled(zap.FatalLevel) {
+		l = GetLogger()
+		if traceID := GetTraceIDFromCtx(ctx); traceID != "" {
+			l = l.With(zap.String(extension.TraceKey, traceID))
+		}
+	}
+	if fields := getFieldsFromCtx(ctx); len(fields) > 0 {
+		l = l.With(fields...)
+	}
+	if isSuppressed(ctx) {
+		l = l.WithOptions(zap.IncreaseLevel(zap.ErrorLevel))
+	}
+	return l
+}
+
+// SuppressBelowError - Return a copy of ctx whose logs below error are dropped, e.g. for health check handlers.
+// It applies to the logs written with ctx and the contexts derived from it through GetTraceLogFromCtx
+// (e.g. log.Info(ctx, ...)) and TraceLogger, not to the other loggers.
+func SuppressBelowError(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyForSuppress, true)
+}
+
+func isSuppressed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	suppressed, _ := ctx.Value(contextKeyForSuppress).(bool)
+	return suppressed
+}
+
+// WithFields - Return a copy of ctx carrying fields, added to every log written with it through
+// GetTraceLogFromCtx (e.g. log.Info(ctx, ...)) on top of the fields of the previous calls.
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	prev := getFieldsFromCtx(ctx)
+	merged := make([]zap.Field, 0, len(prev)+len(fields))
+	merged = append(merged, prev...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, contextKeyForFields, merged)
+}
+
+func getFieldsFromCtx(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
 	}
-	return GetLogger()
+	fields, _ := ctx.Value(contextKeyForFields).([]zap.Field)
+	return fields
 }
 
 func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
@@ -59,6 +147,10 @@ type spanContextCtxKey string
 const (
 	// contextKeyForSpanContext is the key in the context for SpanContext
 	contextKeyForSpanContext = spanContextCtxKey("sc")
+	// contextKeyForFields is the key in the context for the fields added by WithFields
+	contextKeyForFields = spanContextCtxKey("fields")
+	// contextKeyForSuppress is the key in the context for the marker set by SuppressBelowError
+	contextKeyForSuppress = spanContextCtxKey("suppress")
 )
 
 // WithSpanContext sets the SpanContext in context
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caser789/logger/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestTraceLogger(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	loggerInitOnce.Do(func() {})
+	oldLogger := logger.Load()
+	logger.Store(l)
+	defer func() {
+		logger.Store(oldLogger)
+	}()
+
+	sc := trace.NewSpanContextGenerator("").NewSpanContext()
+	ctx := WithFields(WithSpanContext(context.Background(), sc), zap.String("user_id", "u1"))
+	tl := TraceLogger(ctx)
+	tl.Info("first")
+	tl.Info("second")
+	out := read()
+	assert.Contains(t, out, "|"+sc.String()+"|first|")
+	assert.Contains(t, out, "|"+sc.String()+"|second|")
+	assert.Contains(t, out, `"user_id":"u1"`)
+
+	// the logger set by WithNewTraceLog already carries the trace id
+	ctx, _ = WithNewTraceLog("op", context.Background())
+	tl = TraceLogger(ctx)
+	tl.Info("third")
+	assert.Contains(t, read(), "|"+GetTraceIDFromCtx(ctx)+"|third")
+}
+
+func TestSuppressBelowError(t *testing.T) {
+	l, read := newTestLogger(t, &Config{})
+	ctx := SuppressBelowError(WithLogger(context.Background(), l))
+	ctx = WithFields(ctx, zap.String("handler", "health"))
+
+	Info(ctx, "suppressed info")
+	Warn(ctx, "suppressed warn")
+	Error(ctx, "passed error")
+	TraceLogger(ctx).Info("suppressed trace logger")
+	Info(WithLogger(context.Background(), l), "not suppressed")
+
+	out := read()
+	assert.NotContains(t, out, "suppressed info")
+	assert.NotContains(t, out, "suppressed warn")
+	assert.NotContains(t, out, "suppressed trace logger")
+	assert.Contains(t, out, `|passed error|{"handler":"health"}`)
+	assert.Contains(t, out, "|not suppressed")
+}
+
+func newBenchmarkContext(b *testing.B) context.Context {
+	loggerInitOnce.Do(func() {})
+	oldLogger := logger.Load()
+	b.Cleanup(func() {
+		logger.Store(oldLogger)
+	})
+	config := &Config{Path: b.TempDir(), FlushInterval: 100 * time.Millisecond}
+	logger.Store(newLogger(getOption(config, "bench", func(lvl LogLevel) bool {
+		return lvl >= InfoLvl
+	})))
+	sc := trace.NewSpanContextGenerator("").NewSpanContext()
+	return WithFields(WithSpanContext(context.Background(), sc), zap.String("user_id", "u1"))
+}
+
+func BenchmarkInfoCtx(b *testing.B) {
+	ctx := newBenchmarkContext(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Info(ctx, "message", zap.Int("i", i))
+	}
+}
+
+func BenchmarkTraceLogger(b *testing.B) {
+	ctx := newBenchmarkContext(b)
+	b.ResetTimer()
+	l := TraceLogger(ctx)
+	for i := 0; i < b.N; i++ {
+		l.Info("message", zap.Int("i", i))
+	}
+}
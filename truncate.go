@@ -0,0 +1,52 @@
+package log
+
+import (
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// truncatedMarker ends the lines truncated by Config.MaxLineBytes.
+const truncatedMarker = "...(truncated)"
+
+// truncatingEncoder truncates the encoded lines longer than max bytes, keeping their line ending.
+type truncatingEncoder struct {
+	zapcore.Encoder
+	max int
+}
+
+func newTruncatingEncoder(enc zapcore.Encoder, max int) zapcore.Encoder {
+	return &truncatingEncoder{Encoder: enc, max: max}
+}
+
+func (e *truncatingEncoder) Clone() zapcore.Encoder {
+	return &truncatingEncoder{Encoder: e.Encoder.Clone(), max: e.max}
+}
+
+func (e *truncatingEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil || buf.Len() <= e.max {
+		return buf, err
+	}
+	line := buf.Bytes()
+	ending := zapcore.DefaultLineEnding
+	if n := len(line); n > 0 && line[n-1] != '\n' {
+		ending = ""
+	}
+	cut := e.max - len(truncatedMarker) - len(ending)
+	if cut < 0 {
+		cut = 0
+	}
+	// don't split a multi-byte character
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	buf.Reset()
+	buf.Write(line[:cut])
+	if len(truncatedMarker)+len(ending) <= e.max {
+		buf.AppendString(truncatedMarker)
+	}
+	buf.AppendString(ending)
+	return buf, nil
+}
@@ -0,0 +1,214 @@
+package log
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/caser789/logger/internal/lumberjack"
+	"github.com/caser789/logger/internal/writer"
+	"github.com/hashicorp/go-multierror"
+)
+
+// BufferedWriterStats are the counters of the buffered writer of a log file.
+type BufferedWriterStats = writer.Stats
+
+// QueueStats are the counters of an async queue, see Config.QueueFullPolicy.
+type QueueStats = writer.QueueStats
+
+// stdoutSinkName is the name of the sinks of the loggers printing into stdout, see AsyncQueueStats.
+const stdoutSinkName = "stdout"
+
+type queueWriter interface {
+	QueueStats() writer.QueueStats
+}
+
+var (
+	fileWritersMutex sync.Mutex
+	// fileWriters are the writers of the log files, by file path.
+	fileWriters = map[string]*lumberjack.Logger{}
+	// openFileWriters are all the writers not closed yet, including those sharing a file path.
+	openFileWriters = map[*lumberjack.Logger]struct{}{}
+	// queueWriters are the writers with an async queue, by the path of the log file they replace.
+	queueWriters = map[string]queueWriter{}
+	// openSinks are the writers other than the log files (e.g. of the Kafka sink) not closed yet,
+	// with the path of the log file they replace.
+	openSinks = map[io.Closer]string{}
+	// globalFiles are the paths of the log files of the package loggers (user, sys, tracing and access), by logger,
+	// or stdout.
+	globalFiles = map[string][]string{}
+)
+
+func registerFileWriter(w *lumberjack.Logger) {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	fileWriters[w.Filename] = w
+	openFileWriters[w] = struct{}{}
+}
+
+// sinkName - Return the name the sinks of opt are recorded by, the path of its log file or stdout.
+func sinkName(opt option) string {
+	if opt.Stdout {
+		return stdoutSinkName
+	}
+	return opt.Filename
+}
+
+// fileWriterOf - Return the last writer created for the log file of opt, nil if there is none.
+func fileWriterOf(opt option) *lumberjack.Logger {
+	if opt.Stdout {
+		return nil
+	}
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	return fileWriters[opt.Filename]
+}
+
+// setGlobalFiles - Record the log files of the package logger name, built with opts.
+func setGlobalFiles(name string, opts ...option) {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	files := make([]string, 0, len(opts))
+	for _, opt := range opts {
+		if file := sinkName(opt); file != "" {
+			files = append(files, file)
+		}
+	}
+	globalFiles[name] = files
+}
+
+// globalFileWriters - Return the writers of the log files of the package loggers.
+func globalFileWriters() []*lumberjack.Logger {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	var writers []*lumberjack.Logger
+	for _, files := range globalFiles {
+		for _, file := range files {
+			if w, ok := fileWriters[file]; ok {
+				writers = append(writers, w)
+			}
+		}
+	}
+	return writers
+}
+
+// closeFileWriters - Flush and close writers, and forget them unless replaced by a new writer of the same file.
+func closeFileWriters(writers []*lumberjack.Logger) error {
+	var res *multierror.Error
+	for _, w := range writers {
+		if err := w.Close(); err != nil {
+			res = multierror.Append(res, err)
+		}
+		fileWritersMutex.Lock()
+		if fileWriters[w.Filename] == w {
+			delete(fileWriters, w.Filename)
+		}
+		delete(openFileWriters, w)
+		fileWritersMutex.Unlock()
+	}
+	return res.ErrorOrNil()
+}
+
+// CloseAll - Flush the logs and close the files and the other sinks (e.g. Kafka) of all the loggers,
+// including the ClosableLoggers but not those of NewLogger, e.g. at the end of a test or before the process exits.
+// A logger used afterwards reopens its file, but drops the logs of the other sinks.
+func CloseAll() error {
+	flushSpanLogs()
+	fileWritersMutex.Lock()
+	sinks := make([]io.Closer, 0, len(openSinks))
+	for w := range openSinks {
+		sinks = append(sinks, w)
+	}
+	writers := make([]*lumberjack.Logger, 0, len(openFileWriters))
+	for w := range openFileWriters {
+		writers = append(writers, w)
+	}
+	fileWritersMutex.Unlock()
+
+	var res *multierror.Error
+	// the sinks first, as they may write into the files
+	if err := closeSinks(sinks); err != nil {
+		res = multierror.Append(res, err)
+	}
+	if err := closeFileWriters(writers); err != nil {
+		res = multierror.Append(res, err)
+	}
+	return res.ErrorOrNil()
+}
+
+// WriterStats - Return the counters of the buffered writers by log file path, including bytes written,
+// flushes, flush errors and the time spent blocked waiting for the file to be written.
+func WriterStats() map[string]BufferedWriterStats {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	stats := make(map[string]BufferedWriterStats, len(fileWriters))
+	for name, w := range fileWriters {
+		stats[name] = w.Stats()
+	}
+	return stats
+}
+
+// registerSink - Record the writer replacing the log file name, to close it with its logger.
+// Its queue is counted by AsyncQueueStats if it has one.
+func registerSink(name string, w io.Closer) {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	openSinks[w] = name
+	if qw, ok := w.(queueWriter); ok {
+		queueWriters[name] = qw
+	}
+}
+
+// globalSinks - Return the writers replacing the log files of the package loggers.
+func globalSinks() []io.Closer {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	files := make(map[string]bool)
+	for _, names := range globalFiles {
+		for _, name := range names {
+			files[name] = true
+		}
+	}
+	var sinks []io.Closer
+	for w, name := range openSinks {
+		if files[name] {
+			sinks = append(sinks, w)
+		}
+	}
+	return sinks
+}
+
+// closeSinks - Close sinks, sending their queued logs, and forget them.
+func closeSinks(sinks []io.Closer) error {
+	// the async cores first, as they may write into the other sinks
+	sort.SliceStable(sinks, func(i, j int) bool {
+		_, async := sinks[i].(*asyncCore)
+		_, other := sinks[j].(*asyncCore)
+		return async && !other
+	})
+	var res *multierror.Error
+	for _, w := range sinks {
+		if err := w.Close(); err != nil {
+			res = multierror.Append(res, err)
+		}
+		fileWritersMutex.Lock()
+		if qw, ok := w.(queueWriter); ok && queueWriters[openSinks[w]] == qw {
+			delete(queueWriters, openSinks[w])
+		}
+		delete(openSinks, w)
+		fileWritersMutex.Unlock()
+	}
+	return res.ErrorOrNil()
+}
+
+// AsyncQueueStats - Return the counters of the async queues (of the Kafka sink or Async) by the path of the log file
+// they replace, or stdout, including the logs queued, dropped and blocked according to Config.QueueFullPolicy.
+func AsyncQueueStats() map[string]QueueStats {
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	stats := make(map[string]QueueStats, len(queueWriters))
+	for name, w := range queueWriters {
+		stats[name] = w.QueueStats()
+	}
+	return stats
+}